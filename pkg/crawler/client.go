@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/z-bool/go-website-clone/pkg/warc"
+)
+
+// HTTPConfig 页面和资源请求共用的HTTP客户端配置
+type HTTPConfig struct {
+	// ProxyString 代理连接字符串，支持http和socks5
+	ProxyString string
+	// UserAgent 自定义用户代理
+	UserAgent string
+	// Timeout 每次尝试（包括读取响应体）的超时时间，0表示不限制（仍受context控制）
+	Timeout time.Duration
+	// InsecureSkipVerify 是否跳过TLS证书校验
+	InsecureSkipVerify bool
+	// Scheduler 限制请求并发数和速率，nil表示不限制
+	Scheduler *Scheduler
+	// Retry 网络错误、超时、429和5xx响应的重试策略
+	Retry RetryConfig
+	// RecordAttempts 每个请求结束后报告URL及实际尝试次数，可以为nil
+	RecordAttempts func(link string, attempts int)
+	// Recorder 将每次实际发出的请求及其响应（包括重试）写入WARC，nil表示不记录
+	Recorder *warc.Writer
+}
+
+type cancelableTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+func (t cancelableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(req.WithContext(t.ctx))
+}
+
+// userAgentTransport 为没有设置User-Agent的请求补充配置的用户代理
+type userAgentTransport struct {
+	userAgent string
+	transport http.RoundTripper
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// NewHTTPClient 根据配置创建页面和资源共用的HTTP客户端，
+// 请求会使用同一个代理、cookie jar和用户代理，临时错误按重试策略重试，并在ctx取消时中止
+func NewHTTPClient(ctx context.Context, cookieJar http.CookieJar, config HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.ProxyString != "" {
+		proxyURL, err := url.Parse(config.ProxyString)
+		if err != nil {
+			return nil, fmt.Errorf("解析代理地址失败 %q: %w", config.ProxyString, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if config.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	// 每次重试都重新排队，等待重试期间不占用调度名额
+	var rt http.RoundTripper = transport
+	if config.Recorder != nil {
+		rt = &warc.Transport{Writer: config.Recorder, Transport: rt}
+	}
+	if config.Scheduler != nil {
+		rt = config.Scheduler.Transport(rt)
+	}
+	rt = retryTransport{
+		config:    config.Retry.withDefaults(),
+		timeout:   config.Timeout,
+		transport: rt,
+		onDone:    config.RecordAttempts,
+	}
+
+	// 超时由重试层按每次尝试计算，客户端本身不设置总超时
+	return &http.Client{
+		Transport: userAgentTransport{
+			userAgent: config.UserAgent,
+			transport: cancelableTransport{ctx: ctx, transport: rt},
+		},
+		Jar: cookieJar,
+	}, nil
+}
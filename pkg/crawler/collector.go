@@ -2,21 +2,42 @@ package crawler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/html"
+	"github.com/z-bool/go-website-clone/pkg/parser"
 )
 
 // Collector searches for css, js, and images within a given link
 // TODO improve for better performance
 func Collector(ctx context.Context, url string, projectPath string, cookieJar *cookiejar.Jar, proxyString string, userAgent string) error {
+	// 页面和资源共用同一个HTTP客户端（代理、cookie、用户代理和context）
+	var jar http.CookieJar
+	if cookieJar != nil {
+		jar = cookieJar
+	}
+	client, err := NewHTTPClient(ctx, jar, HTTPConfig{ProxyString: proxyString, UserAgent: userAgent})
+	if err != nil {
+		return err
+	}
+
 	// create a new collector
 	c := colly.NewCollector(colly.Async(true))
-	setUpCollector(c, ctx, cookieJar, proxyString, userAgent)
+	useClient(c, client, userAgent)
 
 	// search for all link tags that have a rel attribute that is equal to stylesheet - CSS
 	c.OnHTML("link[rel='stylesheet']", func(e *colly.HTMLElement) {
@@ -25,7 +46,9 @@ func Collector(ctx context.Context, url string, projectPath string, cookieJar *c
 		// print css file was found
 		fmt.Println("Css found", "-->", link)
 		// extraction
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
+		if err := ExtractorWithClient(client, e.Request.AbsoluteURL(link), projectPath); err != nil {
+			fmt.Printf("下载失败: %s (%v)\n", link, err)
+		}
 	})
 
 	// search for all script tags with src attribute -- JS
@@ -35,7 +58,9 @@ func Collector(ctx context.Context, url string, projectPath string, cookieJar *c
 		// Print link
 		fmt.Println("Js found", "-->", link)
 		// extraction
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
+		if err := ExtractorWithClient(client, e.Request.AbsoluteURL(link), projectPath); err != nil {
+			fmt.Printf("下载失败: %s (%v)\n", link, err)
+		}
 	})
 
 	// serach for all img tags with src attribute -- Images
@@ -48,7 +73,9 @@ func Collector(ctx context.Context, url string, projectPath string, cookieJar *c
 		// Print link
 		fmt.Println("Img found", "-->", link)
 		// extraction
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
+		if err := ExtractorWithClient(client, e.Request.AbsoluteURL(link), projectPath); err != nil {
+			fmt.Printf("下载失败: %s (%v)\n", link, err)
+		}
 	})
 
 	// 获取完整的HTML文档 - 改用OnResponse来获取原始HTML
@@ -69,7 +96,9 @@ func Collector(ctx context.Context, url string, projectPath string, cookieJar *c
 			// 检查内容类型，确保是HTML
 			contentType := r.Headers.Get("Content-Type")
 			if strings.Contains(strings.ToLower(contentType), "text/html") {
-				HTMLExtractorFromResponse(currentURL, projectPath, htmlContent)
+				if err := HTMLExtractorFromResponse(currentURL, projectPath, "index.html", htmlContent); err != nil {
+					fmt.Printf("保存页面失败: %v\n", err)
+				}
 			} else {
 				fmt.Printf("跳过非HTML内容: %s\n", contentType)
 			}
@@ -86,140 +115,638 @@ func Collector(ctx context.Context, url string, projectPath string, cookieJar *c
 	return nil
 }
 
-// CollectorWithSizeLimit 带大小限制的收集器
-func CollectorWithSizeLimit(ctx context.Context, url string, projectPath string, cookieJar *cookiejar.Jar, proxyString string, userAgent string, maxFolderSize int64) error {
-	// 在开始下载前检查当前大小
+// CollectorWithSizeLimit 带大小限制的收集器，按配置的深度和页面数递归抓取同站页面
+func CollectorWithSizeLimit(ctx context.Context, targetURL string, projectPath string, cookieJar *cookiejar.Jar, config CrawlConfig) (*Result, error) {
+	return CollectorWithStorage(ctx, targetURL, file.NewDirStorage(projectPath), cookieJar, config)
+}
+
+// CollectorWithStorage 与CollectorWithSizeLimit相同，页面、资源和爬取进度都通过project读写，
+// 项目可以直接保存在内存或其他输出后端中
+func CollectorWithStorage(ctx context.Context, targetURL string, project file.Storage, cookieJar *cookiejar.Jar, config CrawlConfig) (*Result, error) {
+	maxFolderSize := config.GetMaxFolderSize()
+	maxDepth := config.GetMaxDepth()
+	if maxDepth < 0 {
+		maxDepth = 0
+	}
+	maxPages := config.GetMaxPages()
+
+	// 本地目录中上次写入中断（如进程被终止）时遗留的临时文件不再需要，继续克隆前清理，避免计入大小和归档；
+	// 其他后端的写入在Commit前不可见，不会遗留临时文件
+	local, isLocal := project.(*file.DirStorage)
+	if isLocal {
+		if removed, err := file.RemoveTempFiles(local.Root()); err != nil {
+			fmt.Printf("清理临时文件失败: %v\n", err)
+		} else if removed > 0 {
+			fmt.Printf("已清理 %d 个写入中断时遗留的临时文件\n", removed)
+		}
+	}
+
+	// 在开始下载前统计一次当前大小，之后所有写入共用内存中的字节预算
+	var budget *file.Budget
 	if maxFolderSize > 0 {
-		withinLimit, currentSize, err := file.CheckFolderSizeLimit(projectPath, maxFolderSize)
+		currentSize, err := file.StorageSize(project)
 		if err != nil {
-			return fmt.Errorf("检查文件夹大小失败: %w", err)
+			return nil, fmt.Errorf("检查文件夹大小失败: %w", err)
 		}
-		if !withinLimit {
-			return fmt.Errorf("文件夹大小已超过限制: 当前 %d 字节, 限制 %d 字节", currentSize, maxFolderSize)
+		if currentSize > maxFolderSize {
+			return nil, fmt.Errorf("文件夹大小已超过限制: 当前 %d 字节, 限制 %d 字节", currentSize, maxFolderSize)
 		}
 		fmt.Printf("当前文件夹大小: %d 字节 (限制: %d 字节)\n", currentSize, maxFolderSize)
+		budget = file.NewBudget(maxFolderSize, currentSize)
+	}
+	// 单个文件和各类资源的配额同时计入文件夹预算
+	limits := newDownloadLimits(budget, config.GetSizeLimits())
+
+	startURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析URL失败 %q: %w", targetURL, err)
+	}
+
+	// 在访问页面或下载资源前检查爬取范围
+	scope, err := NewScope(startURL, config.GetScope())
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult()
+
+	// 继续上次的克隆时从项目中保存的进度开始，已保存的页面和资源不再下载；
+	// 更新模式重新爬取，按上次的记录发送条件请求，只下载有变化的页面和资源
+	state := NewState(targetURL)
+	previous := NewState(targetURL)
+	update := config.GetUpdate()
+	if update || config.GetResume() {
+		saved, err := LoadStateFrom(project)
+		switch {
+		case err == nil && saved.StartURL == state.StartURL && update:
+			previous = saved
+			result.Manifest = previous.Manifest()
+			fmt.Printf("更新已有的克隆: 上次保存了 %d 个页面, %d 个资源\n", len(previous.Pages), len(previous.Assets))
+		case err == nil && saved.StartURL == state.StartURL:
+			state = saved
+			result.Manifest = state.Manifest()
+			pages, assets := state.pending()
+			fmt.Printf("继续上次的克隆: 已保存 %d 个页面, %d 个资源, 待抓取 %d 个页面, %d 个资源\n",
+				len(state.Pages), len(state.Assets), len(pages), len(assets))
+		case err == nil:
+			fmt.Printf("爬取进度的起始页面 %s 与 %s 不同，重新开始\n", saved.StartURL, state.StartURL)
+		case !errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("读取爬取进度失败，重新开始: %v\n", err)
+		}
+	}
+
+	// 同一资源可能被多个页面引用，只下载一次；值为下载完成时关闭的channel
+	var extracted sync.Map
+	// 请求ID到页面中发现的链接和资源，页面保存时记录到爬取进度
+	var refs sync.Map
+	// 页面数量限制包括之前已保存的页面
+	pageCount := int32(state.savedPages())
+
+	// 页面和资源共用同一个HTTP客户端（代理、cookie、用户代理和context），
+	// 并由同一个调度器限制并发数和请求速率
+	scheduler := NewScheduler(ctx, config.GetDownloadConfig())
+	var jar http.CookieJar
+	if cookieJar != nil {
+		jar = cookieJar
+	}
+	client, err := NewHTTPClient(ctx, jar, HTTPConfig{
+		ProxyString:        config.GetProxyString(),
+		UserAgent:          config.GetUserAgent(),
+		Timeout:            config.GetRequestTimeout(),
+		InsecureSkipVerify: config.GetInsecureSkipVerify(),
+		Scheduler:          scheduler,
+		Retry:              config.GetRetryConfig(),
+		RecordAttempts:     result.recordAttempts,
+		Recorder:           config.GetWARCWriter(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 页面保存前交给渲染器处理，默认原样保存
+	renderer := config.GetRenderer()
+	if renderer == nil {
+		renderer = PassThroughRenderer{}
+	}
+	fetch := renderFetch(ctx, client, limits)
+
+	// 记录接口响应时保留项目中之前的记录，本次克隆再次请求的接口覆盖旧的响应
+	var recordings *file.Recordings
+	var recorded sync.Map
+	if config.GetCaptureAPI() {
+		if config.GetRenderer() == nil {
+			fmt.Println("未设置渲染器，不会记录接口响应")
+		}
+		recordings, err = file.LoadRecordingsFrom(project)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				fmt.Printf("读取%s失败，重新记录: %v\n", file.RecordingsName, err)
+			}
+			recordings = file.NewRecordings()
+		}
 	}
 
-	// 创建新的收集器
+	// 创建新的收集器，页面深度记录在爬取进度中，继续克隆时保持不变
 	c := colly.NewCollector(colly.Async(true))
-	setUpCollector(c, ctx, cookieJar, proxyString, userAgent)
+	useClient(c, client, config.GetUserAgent())
 
-	// 在每次下载前检查大小限制
-	c.OnHTML("link[rel='stylesheet']", func(e *colly.HTMLElement) {
-		if maxFolderSize > 0 {
-			if withinLimit, currentSize, err := file.CheckFolderSizeLimit(projectPath, maxFolderSize); err != nil || !withinLimit {
-				if err != nil {
-					fmt.Printf("检查文件夹大小失败: %v\n", err)
-				} else {
-					fmt.Printf("跳过CSS文件，文件夹大小超限: %d/%d 字节\n", currentSize, maxFolderSize)
-				}
-				return
+	// extract 下载资源并返回本地路径，超出大小预算的资源被跳过，样式表引用的资源会递归下载
+	var extract func(kind string, link string) string
+	extract = func(kind string, link string) string {
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			return ""
+		}
+		if asset, ok := state.asset(link); ok {
+			return asset.Path
+		}
+		done := make(chan struct{})
+		if pending, loaded := extracted.LoadOrStore(parser.NormalizeURL(link), done); loaded {
+			// 已记录本地路径（包括正在处理的循环引用）时直接返回，否则等待其他任务下载完成
+			if localPath, ok := result.Manifest.Asset(link); ok {
+				return localPath
 			}
+			<-pending.(chan struct{})
+			localPath, _ := result.Manifest.Asset(link)
+			return localPath
 		}
-		link := e.Attr("href")
-		fmt.Println("Css found", "-->", link)
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
-	})
+		defer close(done)
+		if u, err := url.Parse(link); err != nil || !scope.AllowAsset(u) {
+			fmt.Printf("跳过范围外的%s资源: %s\n", kind, link)
+			state.dropAsset(link)
+			return ""
+		}
+		var known *AssetState
+		if asset, ok := previous.asset(link); ok {
+			known = &asset
+		}
+		saved, err := extractAsset(client, link, project, result, limits, known, func(ref string) string {
+			fmt.Println("Css ref found", "-->", ref)
+			state.queueAsset(ref, "CSS引用")
+			return extract("CSS引用", ref)
+		})
+		switch {
+		case err != nil && gone(err):
+			// 已不存在的资源不再重试，更新时从项目中删除
+			result.addFailure(link, err)
+			state.dropAsset(link)
+		case known != nil && (err != nil || saved.Path == ""):
+			// 更新时下载失败或被跳过（如超出大小限制）的资源沿用上次保存的文件
+			if err != nil {
+				result.addFailure(link, err)
+			}
+			state.assetSaved(link, *known)
+			return known.Path
+		case err != nil:
+			// 下载失败的资源保留在待下载列表中，继续克隆时重试
+			result.addFailure(link, err)
+		case saved.Path == "":
+			state.dropAsset(link)
+		default:
+			state.assetSaved(link, saved.AssetState)
+		}
+		return saved.Path
+	}
 
-	c.OnHTML("script[src]", func(e *colly.HTMLElement) {
-		if maxFolderSize > 0 {
-			if withinLimit, currentSize, err := file.CheckFolderSizeLimit(projectPath, maxFolderSize); err != nil || !withinLimit {
-				if err != nil {
-					fmt.Printf("检查文件夹大小失败: %v\n", err)
-				} else {
-					fmt.Printf("跳过JS文件，文件夹大小超限: %d/%d 字节\n", currentSize, maxFolderSize)
-				}
+	// queueAsset 记录页面中的资源并交给调度器在后台下载
+	queueAsset := func(r *colly.Request, kind string, link string) {
+		found := pageRefsOf(&refs, r.ID)
+		found.assets = append(found.assets, link)
+		if _, ok := state.asset(link); ok {
+			return
+		}
+		// 先记录到待下载列表，页面标记为已保存后中断也不会遗漏其资源
+		state.queueAsset(link, kind)
+		scheduler.Go(func() {
+			extract(kind, link)
+		})
+	}
+
+	// followLink 记录页面中的同站链接，未超过最大深度时加入队列并访问
+	followLink := func(r *colly.Request, link string, depth int) {
+		found := pageRefsOf(&refs, r.ID)
+		found.links = append(found.links, link)
+		if depth >= maxDepth || !state.queuePage(link, depth+1) {
+			return
+		}
+		if err := r.Visit(link); err != nil {
+			state.dropPage(link)
+			return
+		}
+		fmt.Println("Page found", "-->", link)
+	}
+
+	// 未保存的页面不下载其资源
+	for _, asset := range html.AssetAttrs {
+		asset := asset
+		c.OnHTML(asset.Selector, func(e *colly.HTMLElement) {
+			if pageSkipped(e.Request) {
 				return
 			}
-		}
-		link := e.Attr("src")
-		fmt.Println("Js found", "-->", link)
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
-	})
+			for _, link := range assetLinks(asset, e.Attr(asset.Attr)) {
+				fmt.Println(asset.Kind, "found", "-->", link)
+				queueAsset(e.Request, asset.Kind, e.Request.AbsoluteURL(link))
+			}
+		})
+	}
 
-	c.OnHTML("img[src]", func(e *colly.HTMLElement) {
-		if maxFolderSize > 0 {
-			if withinLimit, currentSize, err := file.CheckFolderSizeLimit(projectPath, maxFolderSize); err != nil || !withinLimit {
-				if err != nil {
-					fmt.Printf("检查文件夹大小失败: %v\n", err)
-				} else {
-					fmt.Printf("跳过图片文件，文件夹大小超限: %d/%d 字节\n", currentSize, maxFolderSize)
-				}
+	// 跟随同站链接抓取更多页面
+	if maxDepth > 0 {
+		c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+			if pageSkipped(e.Request) {
 				return
 			}
-		}
-		link := e.Attr("src")
-		if strings.HasPrefix(link, "data:image") || strings.HasPrefix(link, "blob:") {
+			if link := pageLink(scope, e.Request.AbsoluteURL(e.Attr("href"))); link != "" {
+				followLink(e.Request, link, requestDepth(e.Request))
+			}
+		})
+	}
+
+	// 限制抓取的页面总数，并记录重定向前的原始URL和页面深度
+	c.OnRequest(func(r *colly.Request) {
+		if maxPages > 0 && int(atomic.AddInt32(&pageCount, 1)) > maxPages {
+			// 未抓取的页面留在队列中，继续克隆时可以提高限制
+			fmt.Printf("已达到页面数量限制 %d，跳过: %s\n", maxPages, r.URL)
+			r.Abort()
 			return
 		}
-		fmt.Println("Img found", "-->", link)
-		Extractor(e.Request.AbsoluteURL(link), projectPath)
+		depth, ok := state.pageDepth(r.URL.String())
+		if !ok {
+			depth = maxDepth
+		}
+		// 子页面请求共用父页面的Ctx，按请求ID区分
+		r.Ctx.Put(requestURLKey(r.ID), r.URL.String())
+		r.Ctx.Put(pageDepthKey(r.ID), strconv.Itoa(depth))
+		if page, ok := previous.page(r.URL.String()); ok {
+			setConditionalHeaders(*r.Headers, page.ETag, page.LastModified)
+		}
 	})
 
 	// 获取完整的HTML文档
 	c.OnResponse(func(r *colly.Response) {
 		currentURL := r.Request.URL.String()
-		fmt.Printf("获取完整响应: %s (目标URL: %s)\n", currentURL, url)
+		contentType := r.Headers.Get("Content-Type")
+		requestURL := r.Ctx.Get(requestURLKey(r.Request.ID))
+		if !strings.Contains(strings.ToLower(contentType), "text/html") {
+			fmt.Printf("跳过非HTML内容: %s (%s)\n", currentURL, contentType)
+			state.dropPage(requestURL)
+			return
+		}
 
-		normalizedCurrentURL := strings.TrimSuffix(currentURL, "/")
-		normalizedTargetURL := strings.TrimSuffix(url, "/")
+		// 渲染后的HTML代替原始响应保存，并用于查找资源和链接；渲染失败时保存原始HTML
+		var resources []string
+		var responses map[string]*Fetched
+		rendered, err := renderer.Render(ctx, RenderPage{URL: currentURL, HTML: r.Body, Fetch: fetch})
+		if err != nil {
+			fmt.Printf("渲染页面失败，保存原始HTML: %s (%v)\n", currentURL, err)
+		} else {
+			r.Body, resources, responses = rendered.HTML, rendered.Resources, rendered.Responses
+		}
 
-		if normalizedCurrentURL == normalizedTargetURL {
-			fmt.Printf("保存主页面HTML: %s\n", currentURL)
-			fmt.Printf("Content-Type: %s\n", r.Headers.Get("Content-Type"))
-			htmlContent := r.Body
-			fmt.Printf("HTML内容长度: %d 字节\n", len(htmlContent))
+		// 起始页面始终保存为index.html
+		pagePath := "index.html"
+		if requestDepth(r.Request) > 0 {
+			pagePath = parser.PagePath(currentURL)
+		}
 
-			contentType := r.Headers.Get("Content-Type")
-			if strings.Contains(strings.ToLower(contentType), "text/html") {
-				HTMLExtractorFromResponse(currentURL, projectPath, htmlContent)
-			} else {
-				fmt.Printf("跳过非HTML内容: %s\n", contentType)
+		// 更新时新内容替换上次保存的页面，只按大小差额计入文件夹预算
+		restore := func() {}
+		if page, ok := previous.page(requestURL); ok {
+			restore = limits.replace(fileSize(project, page.Path))
+		}
+
+		// 超出大小限制的页面不保存，链接重构时保留原始URL
+		size := int64(len(r.Body))
+		err = ErrFileTooLarge
+		if !limits.tooLarge(size) {
+			err = budget.Reserve(size)
+		}
+		if err != nil {
+			restore()
+			result.addSkip(currentURL, "html", fmt.Sprintf("%v: %d 字节", err, size))
+			r.Ctx.Put(pageSkippedKey(r.Request.ID), "1")
+			state.dropPage(requestURL)
+			return
+		}
+		pagePath = result.Manifest.AddPage(currentURL, pagePath, requestURL)
+
+		fmt.Printf("保存页面HTML: %s -> %s\n", currentURL, pagePath)
+		fmt.Printf("Content-Type: %s\n", contentType)
+		if err := savePage(currentURL, project, pagePath, r.Body); err != nil {
+			budget.Release(size)
+			restore()
+			result.addFailure(currentURL, err)
+			return
+		}
+		r.Ctx.Put(pagePathKey(r.Request.ID), pagePath)
+		for _, link := range resources {
+			queueAsset(r.Request, "渲染请求", link)
+		}
+		if recordings != nil {
+			recordResponses(project, recordings, &recorded, result, limits, responses)
+		}
+	})
+
+	// 页面中的链接和资源都已加入队列后才将页面标记为已保存
+	c.OnScraped(func(r *colly.Response) {
+		found, _ := refs.LoadAndDelete(r.Request.ID)
+		pagePath := r.Ctx.Get(pagePathKey(r.Request.ID))
+		if pagePath == "" {
+			return
+		}
+		sum := sha256.Sum256(r.Body)
+		page := PageState{
+			Path:         pagePath,
+			Depth:        requestDepth(r.Request),
+			SHA256:       hex.EncodeToString(sum[:]),
+			ETag:         r.Headers.Get("ETag"),
+			LastModified: r.Headers.Get("Last-Modified"),
+		}
+		if found != nil {
+			page.Links, page.Assets = found.(*pageRefs).links, found.(*pageRefs).assets
+		}
+		state.pageSaved(r.Request.URL.String(), r.Ctx.Get(requestURLKey(r.Request.ID)), page)
+	})
+
+	// 页面请求失败只记录，不中断其他页面
+	c.OnError(func(r *colly.Response, err error) {
+		requestURL := r.Ctx.Get(requestURLKey(r.Request.ID))
+		// 更新模式下未修改的页面沿用本地文件，按上次记录的链接和资源继续抓取
+		if page, ok := previous.page(requestURL); ok && r.StatusCode == http.StatusNotModified {
+			fmt.Printf("页面未修改: %s\n", r.Request.URL)
+			result.Manifest.AddPage(r.Request.URL.String(), page.Path, requestURL)
+			for _, link := range page.Assets {
+				queueAsset(r.Request, "资源", link)
 			}
-		} else {
-			fmt.Printf("URL不匹配，跳过响应: %s vs %s\n", normalizedCurrentURL, normalizedTargetURL)
+			depth := requestDepth(r.Request)
+			for _, link := range page.Links {
+				followLink(r.Request, link, depth)
+			}
+			refs.Delete(r.Request.ID)
+			page.Depth = depth
+			page.ETag, page.LastModified = validators(*r.Headers, page.ETag, page.LastModified)
+			state.pageSaved(r.Request.URL.String(), requestURL, page)
+			return
+		}
+		result.addFailure(r.Request.URL.String(), err)
+		if r.StatusCode == http.StatusNotFound || r.StatusCode == http.StatusGone {
+			state.dropPage(requestURL)
 		}
 	})
 
-	if err := c.Visit(url); err != nil {
-		return err
+	// 定期保存爬取进度，进程中断后可以继续
+	stop := make(chan struct{})
+	var saving sync.WaitGroup
+	saving.Add(1)
+	go func() {
+		defer saving.Done()
+		state.saveEvery(project, stateSaveInterval, stop)
+	}()
+
+	// 起始页面尚未保存时从它开始，继续克隆时同时访问上次队列中的页面和待下载的资源
+	state.queuePage(targetURL, 0)
+	pages, assets := state.pending()
+	for pageURL := range pages {
+		if err := c.Visit(pageURL); err != nil {
+			if pageURL == state.StartURL {
+				close(stop)
+				saving.Wait()
+				return nil, err
+			}
+			state.dropPage(pageURL)
+		}
+	}
+	for link, kind := range assets {
+		link, kind := link, kind
+		scheduler.Go(func() {
+			extract(kind, link)
+		})
 	}
 	c.Wait()
+	scheduler.Wait()
+	close(stop)
+	saving.Wait()
+
+	fmt.Printf("共保存 %d 个页面, %d 个资源\n", len(result.Manifest.PagePaths()), len(result.Manifest.Assets))
+
+	if update {
+		// 队列中还有页面（请求失败、超出页面数量限制或被取消）时无法确定哪些内容已不存在，不删除
+		pages, _ := state.pending()
+		var removed []removedEntry
+		result.Changes, removed = state.changes(previous, len(pages) == 0 && ctx.Err() == nil)
+		removeEntries(project, result.Manifest, removed, limits)
+		fmt.Printf("更新完成: 新增 %d, 修改 %d, 删除 %d\n", len(result.Changes.Added), len(result.Changes.Modified), len(result.Changes.Removed))
+	}
+
+	// 使用共享存储时，资源与其他项目中内容相同的文件共用一份（硬链接，只支持本地目录）
+	if store := config.GetStore(); store != nil && isLocal {
+		shareAssets(store, local.Root(), result.Manifest, state)
+	} else if store != nil {
+		fmt.Println("项目不在本地目录中，不使用共享存储")
+	}
+
+	if err := result.Manifest.SaveTo(project); err != nil {
+		return nil, fmt.Errorf("保存%s失败: %w", file.ManifestName, err)
+	}
+	if err := state.SaveTo(project); err != nil {
+		return nil, fmt.Errorf("保存%s失败: %w", StateName, err)
+	}
+	if recordings != nil {
+		if err := recordings.SaveTo(project); err != nil {
+			return nil, fmt.Errorf("保存%s失败: %w", file.RecordingsName, err)
+		}
+		fmt.Printf("共记录 %d 个接口响应\n", recordings.Len())
+	}
+	if !state.Complete {
+		pages, assets := state.pending()
+		fmt.Printf("仍有 %d 个页面, %d 个资源未完成，可使用Resume继续\n", len(pages), len(assets))
+	}
 
-	// 最终大小检查和报告
+	// 最终大小报告
 	if maxFolderSize > 0 {
-		finalSize, err := file.GetFolderSize(projectPath)
-		if err != nil {
-			fmt.Printf("无法计算最终文件夹大小: %v\n", err)
+		fmt.Printf("最终文件夹大小: %d 字节 (限制: %d 字节)\n", budget.Used(), maxFolderSize)
+		for category, count := range result.SkippedByCategory() {
+			fmt.Printf("因大小限制等原因跳过 %d 个%s\n", count, category)
+		}
+	}
+
+	return result, nil
+}
+
+// pageRefs 页面中发现的同站链接和资源
+type pageRefs struct {
+	links  []string
+	assets []string
+}
+
+// pageRefsOf 返回请求对应的pageRefs，同一页面的OnHTML回调按顺序执行
+func pageRefsOf(refs *sync.Map, id uint32) *pageRefs {
+	found, _ := refs.LoadOrStore(id, &pageRefs{})
+	return found.(*pageRefs)
+}
+
+// removeEntries 删除已不存在的页面和资源的记录及本地文件
+func removeEntries(project file.Storage, manifest *file.Manifest, removed []removedEntry, limits *downloadLimits) {
+	for _, entry := range removed {
+		if entry.page {
+			manifest.RemovePage(entry.url)
 		} else {
-			fmt.Printf("最终文件夹大小: %d 字节 (限制: %d 字节)\n", finalSize, maxFolderSize)
+			manifest.RemoveAsset(entry.url)
+		}
+		if entry.keepFile {
+			continue
+		}
+		size := fileSize(project, entry.path)
+		if err := project.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("删除 %s 失败: %v\n", entry.path, err)
+			continue
 		}
+		limits.free(size)
+		fmt.Printf("已删除: %s (%s)\n", entry.path, entry.url)
 	}
+}
 
-	return nil
+// fileSize 返回项目中文件的大小，文件不存在时返回0
+func fileSize(project file.Storage, rel string) int64 {
+	info, err := project.Stat(rel)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
 }
 
-type cancelableTransport struct {
-	ctx       context.Context
-	transport http.RoundTripper
+// recordResponses 将页面渲染时请求的接口响应保存到项目中，多个页面请求同一接口时只记录一次，
+// 响应体计入文件夹大小预算；被跳过或保存失败的接口不算已记录，之后的页面再次请求时重试
+func recordResponses(project file.Storage, recordings *file.Recordings, recorded *sync.Map, result *Result, limits *downloadLimits, responses map[string]*Fetched) {
+	links := make([]string, 0, len(responses))
+	for link := range responses {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		if _, loaded := recorded.LoadOrStore(link, true); loaded {
+			continue
+		}
+		fetched := responses[link]
+		size := int64(len(fetched.Body))
+		budget := limits.budget("api")
+		if err := budget.Reserve(size); err != nil {
+			recorded.Delete(link)
+			result.addSkip(link, "api", fmt.Sprintf("%v: %d 字节", err, size))
+			continue
+		}
+		recording, err := recordings.AddTo(project, http.MethodGet, link, fetched.Status, fetched.Header.Get("Content-Type"), fetched.Body)
+		if err != nil {
+			budget.Release(size)
+			recorded.Delete(link)
+			result.addFailure(link, err)
+			continue
+		}
+		fmt.Printf("记录接口响应: %s -> %s\n", link, recording.Path)
+	}
 }
 
-func (t cancelableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if err := t.ctx.Err(); err != nil {
-		return nil, err
+// shareAssets 将已保存的资源放入共享存储，并在资源清单中记录对应的blob，
+// 放入失败的资源仍保留在项目中，只是不与其他项目共用
+func shareAssets(store *file.Store, projectPath string, manifest *file.Manifest, state *State) {
+	if err := store.Register(projectPath); err != nil {
+		fmt.Printf("登记项目到共享存储失败: %v\n", err)
+		return
+	}
+	shared := 0
+	for link, asset := range state.savedAssets() {
+		sum, err := store.Link(projectPath, asset.Path, asset.SHA256)
+		if err != nil {
+			fmt.Printf("放入共享存储失败: %s (%v)\n", link, err)
+			continue
+		}
+		manifest.SetBlob(asset.Path, sum)
+		shared++
 	}
-	return t.transport.RoundTrip(req.WithContext(t.ctx))
+	fmt.Printf("%d 个资源已放入共享存储: %s\n", shared, store.Root())
 }
 
-func setUpCollector(c *colly.Collector, ctx context.Context, cookieJar *cookiejar.Jar, proxyString, userAgent string) {
-	if cookieJar != nil {
-		c.SetCookieJar(cookieJar)
+// requestURLKey 返回在colly上下文中保存请求原始URL的键
+func requestURLKey(id uint32) string {
+	return "requestURL:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// pageDepthKey 返回在colly上下文中保存页面深度的键
+func pageDepthKey(id uint32) string {
+	return "pageDepth:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// pagePathKey 返回在colly上下文中保存页面本地路径的键
+func pagePathKey(id uint32) string {
+	return "pagePath:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// requestDepth 返回请求的页面深度，起始页面为0
+func requestDepth(r *colly.Request) int {
+	depth, _ := strconv.Atoi(r.Ctx.Get(pageDepthKey(r.ID)))
+	return depth
+}
+
+// pageSkippedKey 返回在colly上下文中标记页面未保存的键
+func pageSkippedKey(id uint32) string {
+	return "pageSkipped:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// pageSkipped 判断请求的页面是否因大小限制未保存
+func pageSkipped(r *colly.Request) bool {
+	return r.Ctx.Get(pageSkippedKey(r.ID)) != ""
+}
+
+// assetLinks 返回属性中引用的资源链接，srcset属性可能包含多个链接
+func assetLinks(asset html.AssetAttr, value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "data:") || strings.HasPrefix(value, "blob:") {
+		return nil
+	}
+	if !asset.Srcset {
+		return []string{value}
 	}
-	if proxyString != "" {
-		c.SetProxy(proxyString)
-	} else {
-		c.WithTransport(cancelableTransport{ctx: ctx, transport: http.DefaultTransport})
+
+	var links []string
+	for _, candidate := range parser.ParseSrcset(value) {
+		if !strings.HasPrefix(candidate.URL, "data:") {
+			links = append(links, candidate.URL)
+		}
+	}
+	return links
+}
+
+// pageLink 判断链接是否为需要跟随的范围内页面，返回去除锚点后的URL
+func pageLink(scope *Scope, link string) string {
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	if !scope.AllowPage(u) {
+		return ""
+	}
+	// 静态资源不作为页面抓取
+	if parser.AssetDir(parser.URLExtension(u.Path)) != "" {
+		return ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// useClient 让收集器使用共享HTTP客户端的传输层和cookie jar
+func useClient(c *colly.Collector, client *http.Client, userAgent string) {
+	c.WithTransport(client.Transport)
+	if client.Jar != nil {
+		c.SetCookieJar(client.Jar)
 	}
+	// 超时和重试由共享客户端的传输层处理，不使用colly默认的10秒总超时
+	c.SetRequestTimeout(client.Timeout)
 	if userAgent != "" {
 		c.UserAgent = userAgent
 	}
@@ -0,0 +1,40 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+func TestRecordResponsesRetry(t *testing.T) {
+	project := file.NewMemoryStorage()
+	recordings := file.NewRecordings()
+	var recorded sync.Map
+	// 预算只剩10字节，第一次记录被跳过，之后空间足够时再次请求的页面应当记录
+	budget := file.NewBudget(100, 90)
+	limits := newDownloadLimits(budget, SizeLimits{})
+	link := "https://example.com/api/items"
+	responses := map[string]*Fetched{
+		link: {URL: link, Status: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"items":[1,2,3]}`)},
+	}
+
+	result := newResult()
+	recordResponses(project, recordings, &recorded, result, limits, responses)
+	skipped := len(result.Skipped) == 1 && recordings.Len() == 0
+	budget.Release(90)
+	recordResponses(project, recordings, &recorded, result, limits, responses)
+	_, found := recordings.Lookup(http.MethodGet, "/api/items")
+	if !skipped || !found || recordings.Len() != 1 {
+		t.Error()
+		red := color.New(color.FgRed).SprintFunc()
+		fmt.Printf("%s RecordResponsesRetry Failed: skipped first %v, recorded later %v (%d recordings)\n", red("[-]"), skipped, found, recordings.Len())
+
+	} else {
+		green := color.New(color.FgGreen).SprintFunc()
+		fmt.Printf("%s RecordResponsesRetry Passing \n", green("[+]"))
+	}
+}
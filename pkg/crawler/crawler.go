@@ -2,14 +2,125 @@ package crawler
 
 import (
 	"context"
+	"fmt"
 	"net/http/cookiejar"
+	"sync"
+	"time"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+	"github.com/z-bool/go-website-clone/pkg/warc"
 )
 
 // CrawlConfig 爬取配置接口
 type CrawlConfig interface {
 	GetProxyString() string
 	GetUserAgent() string
+	GetRequestTimeout() time.Duration
+	GetInsecureSkipVerify() bool
 	GetMaxFolderSize() int64
+	GetMaxDepth() int
+	GetMaxPages() int
+	GetScope() ScopeConfig
+	GetDownloadConfig() DownloadConfig
+	GetRetryConfig() RetryConfig
+	GetSizeLimits() SizeLimits
+	GetResume() bool
+	GetUpdate() bool
+	GetWARCWriter() *warc.Writer
+	GetStore() *file.Store
+	GetRenderer() Renderer
+	GetCaptureAPI() bool
+}
+
+// Failure 下载失败的页面或资源
+type Failure struct {
+	// URL 失败的地址
+	URL string
+	// Err 失败原因
+	Err error
+	// Attempts 放弃前的请求次数，0表示没有发出请求
+	Attempts int
+}
+
+// Error 实现error接口
+func (f Failure) Error() string {
+	return fmt.Sprintf("%s: %v", f.URL, f.Err)
+}
+
+// Unwrap 返回失败原因
+func (f Failure) Unwrap() error {
+	return f.Err
+}
+
+// Skip 因大小限制或配额等原因没有保存的页面或资源，链接重构时保留其原始URL
+type Skip struct {
+	// URL 跳过的地址
+	URL string
+	// Category 资源类型（css、js、imgs、fonts、media、misc），页面为html，接口响应为api
+	Category string
+	// Reason 跳过原因
+	Reason string
+}
+
+// Result 爬取结果
+type Result struct {
+	// Manifest 页面和资源URL到本地路径的清单，已保存为项目中的manifest.json
+	Manifest *file.Manifest
+	// Failures 下载失败的页面和资源，单个失败不会中断克隆
+	Failures []Failure
+	// Skipped 因大小限制等原因没有保存的页面和资源
+	Skipped []Skip
+	// Attempts 每个请求过的页面和资源URL的尝试次数（包括重试）
+	Attempts map[string]int
+	// Changes 更新模式下与上次克隆相比的变化
+	Changes Changes
+
+	mu sync.Mutex
+}
+
+func newResult() *Result {
+	return &Result{
+		Manifest: file.NewManifest(),
+		Attempts: make(map[string]int),
+	}
+}
+
+// recordAttempts 记录URL的尝试次数，同一URL多次请求时累加
+func (r *Result) recordAttempts(link string, attempts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Attempts[parser.NormalizeURL(link)] += attempts
+}
+
+// addFailure 记录下载失败的URL及原因
+func (r *Result) addFailure(link string, err error) {
+	fmt.Printf("下载失败: %s (%v)\n", link, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failures = append(r.Failures, Failure{URL: link, Err: err, Attempts: r.Attempts[parser.NormalizeURL(link)]})
+}
+
+// addSkip 记录没有保存的页面或资源
+func (r *Result) addSkip(link string, category string, reason string) {
+	fmt.Printf("跳过%s: %s (%s)\n", category, link, reason)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Skipped = append(r.Skipped, Skip{URL: link, Category: category, Reason: reason})
+}
+
+// SkippedByCategory 按资源类型统计跳过的页面和资源数量
+func (r *Result) SkippedByCategory() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, skip := range r.Skipped {
+		counts[skip.Category]++
+	}
+	return counts
 }
 
 // Crawl asks the necessary crawlers for collecting links for building the web page
@@ -18,7 +129,12 @@ func Crawl(ctx context.Context, site string, projectPath string, cookieJar *cook
 	return Collector(ctx, site, projectPath, cookieJar, proxyString, userAgent)
 }
 
-// CrawlWithConfig 使用配置对象进行爬取，支持大小检查
-func CrawlWithConfig(ctx context.Context, site string, projectPath string, cookieJar *cookiejar.Jar, config CrawlConfig) error {
-	return CollectorWithSizeLimit(ctx, site, projectPath, cookieJar, config.GetProxyString(), config.GetUserAgent(), config.GetMaxFolderSize())
+// CrawlWithConfig 使用配置对象进行爬取，支持大小检查和多页面递归
+func CrawlWithConfig(ctx context.Context, site string, projectPath string, cookieJar *cookiejar.Jar, config CrawlConfig) (*Result, error) {
+	return CollectorWithSizeLimit(ctx, site, projectPath, cookieJar, config)
+}
+
+// CrawlWithStorage 与CrawlWithConfig相同，项目通过输出后端读写，不需要本地目录
+func CrawlWithStorage(ctx context.Context, site string, project file.Storage, cookieJar *cookiejar.Jar, config CrawlConfig) (*Result, error) {
+	return CollectorWithStorage(ctx, site, project, cookieJar, config)
 }
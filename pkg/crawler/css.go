@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+// rewriteCSS 将样式表中的url()和@import引用改写为本地路径，
+// 每个可下载的引用都会通过fetchRef交给调用方下载
+func rewriteCSS(data []byte, cssURL string, fetchRef func(ref string) string) []byte {
+	base, err := url.Parse(cssURL)
+	if err != nil {
+		return data
+	}
+	return parser.RewriteCSSRefs(data, func(ref string) (string, bool) {
+		return localCSSRef(base, ref, fetchRef)
+	})
+}
+
+// localCSSRef 解析CSS中的引用，下载后返回相对于css目录的本地路径
+func localCSSRef(base *url.URL, ref string, fetchRef func(ref string) string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+
+	fragment := u.EscapedFragment()
+	u.Fragment = ""
+	localPath := fetchRef(u.String())
+	if localPath == "" {
+		return "", false
+	}
+
+	// 样式表都保存在css目录下，保留SVG等资源的片段标识
+	local := relativeAssetPath("css", localPath)
+	if fragment != "" {
+		local += "#" + fragment
+	}
+	return local, true
+}
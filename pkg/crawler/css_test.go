@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+func TestRewriteCSS(t *testing.T) {
+	tables := []struct {
+		css      string
+		expected string
+		refs     int
+	}{
+		{`@font-face{src:url("../fonts/a.woff2") format("woff2")}`, `@font-face{src:url("../fonts/a.woff2") format("woff2")}`, 1},
+		{`body{background:url(/static/bg.png?v=2)}`, `body{background:url("../imgs/bg.png")}`, 1},
+		{`@import 'theme.css';`, `@import "theme.css";`, 1},
+		{`@import url("https://cdn.example.net/reset.css");`, `@import url("reset.css");`, 1},
+		{`a{background:url(data:image/png;base64,AAAA)}`, `a{background:url(data:image/png;base64,AAAA)}`, 0},
+		{`a{behavior:url(#default#VML)}`, `a{behavior:url(#default#VML)}`, 0},
+		{`a{cursor:url(/cursors/hand)}`, `a{cursor:url(/cursors/hand)}`, 1},
+		{`a{background:url(/icons.svg#home)}`, `a{background:url("../imgs/icons.svg#home")}`, 1},
+	}
+	for _, table := range tables {
+		var refs []string
+		result := string(rewriteCSS([]byte(table.css), "https://example.com/assets/css/main.css", func(ref string) string {
+			refs = append(refs, ref)
+			dir, name := parser.AssetPath(ref)
+			if dir == "" {
+				return ""
+			}
+			return dir + "/" + name
+		}))
+		if result != table.expected || len(refs) != table.refs {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s RewriteCSS Failed: %s , expected %s got %s (%d refs)\n", red("[-]"), table.css, table.expected, result, len(refs))
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s RewriteCSS Passing: %s \n", green("[+]"), table.css)
+		}
+	}
+}
@@ -1,100 +1,297 @@
 package crawler
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
-	"regexp"
+	"path"
 	"strings"
 
+	"github.com/z-bool/go-website-clone/pkg/file"
 	"github.com/z-bool/go-website-clone/pkg/parser"
 )
 
-// file extension map for directing files to their proper directory in O(1) time
-var (
-	extensionDir = map[string]string{
-		".css":  "css",
-		".js":   "js",
-		".jpg":  "imgs",
-		".jpeg": "imgs",
-		".gif":  "imgs",
-		".png":  "imgs",
-		".svg":  "imgs",
-	}
-)
+// Extractor 通过http.DefaultClient下载资源，不使用配置的代理、cookie和用户代理
+//
+// Deprecated: 使用ExtractorWithClient并传入NewHTTPClient创建的客户端
+func Extractor(link string, projectPath string) error {
+	return ExtractorWithClient(http.DefaultClient, link, projectPath)
+}
 
-// Extractor visits a link determines if its a page or sublink
+// ExtractorWithClient visits a link determines if its a page or sublink
 // downloads the contents to a correct directory in project folder
+// stylesheets are rewritten and everything they reference is downloaded too,
+// failures of referenced resources are skipped, only the link itself is reported;
+// 通过指定的HTTP客户端下载，以便使用与页面相同的代理、cookie和用户代理
 // TODO add functionality for determining if page or sublink
-func Extractor(link string, projectPath string) {
+func ExtractorWithClient(client *http.Client, link string, projectPath string) error {
+	result := newResult()
+	project := file.NewDirStorage(projectPath)
+	seen := make(map[string]bool)
+	var fetch func(ref string) string
+	fetch = func(ref string) string {
+		if seen[ref] {
+			localPath, _ := result.Manifest.Asset(ref)
+			return localPath
+		}
+		seen[ref] = true
+		saved, err := extractAsset(client, ref, project, result, nil, nil, fetch)
+		if err != nil {
+			result.addFailure(ref, err)
+		}
+		return saved.Path
+	}
+
+	seen[link] = true
+	_, err := extractAsset(client, link, project, result, nil, nil, fetch)
+	return err
+}
+
+// savedAsset 已保存资源的本地路径、大小、内容哈希和响应中的缓存验证信息
+type savedAsset struct {
+	AssetState
+	// Unchanged 服务器返回304，沿用项目中已有的文件
+	Unchanged bool
+}
+
+// StatusError 服务器返回了非2xx的状态码
+type StatusError struct {
+	StatusCode int
+}
+
+// Error 实现error接口
+func (e StatusError) Error() string {
+	return fmt.Sprintf("HTTP状态码 %d", e.StatusCode)
+}
+
+// gone 判断错误是否表示资源已不存在（404或410），这类资源不再重试
+func gone(err error) bool {
+	var status StatusError
+	return errors.As(err, &status) && (status.StatusCode == http.StatusNotFound || status.StatusCode == http.StatusGone)
+}
+
+// extractAsset 下载单个资源并返回其在项目中的相对路径，不支持的类型返回空路径和nil；
+// 超出大小限制的资源记录到result.Skipped并返回空路径和nil；
+// CSS和manifest中引用的资源通过fetchRef继续下载；
+// previous为上次保存的记录时发送条件请求，未修改的资源沿用本地文件，下载失败时也保留本地文件
+func extractAsset(client *http.Client, link string, project file.Storage, result *Result, limits *downloadLimits, previous *AssetState, fetchRef func(ref string) string) (savedAsset, error) {
 	fmt.Println("Extracting --> ", link)
 
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return savedAsset{}, err
+	}
+	if previous != nil {
+		setConditionalHeaders(req.Header, previous.ETag, previous.LastModified)
+	}
+
 	// get the html body
-	resp, err := http.Get(link)
+	resp, err := client.Do(req)
 	if err != nil {
-		panic(err)
+		return savedAsset{}, err
 	}
 
 	// Closure
 	defer resp.Body.Close()
-	// file base
-	base := parser.URLFilename(link)
-	// store the old ext, in special cases the ext is weird ".css?a134fv"
-	oldExt := filepath.Ext(base)
-	// new file extension
-	ext := parser.URLExtension(link)
-
-	// checks if there was a valid extension
-	if ext != "" {
-		// checks if that extension has a directory path name associated with it
-		// from the extensionDir map
-		dirPath := extensionDir[ext]
-		if dirPath != "" {
-			// If extension and path are valid pass to writeFileToPath
-			writeFileToPath(projectPath, base, oldExt, ext, dirPath, resp)
+
+	if resp.StatusCode == http.StatusNotModified && previous != nil {
+		resp.Body.Close()
+		fmt.Printf("资源未修改: %s\n", link)
+		unchanged := *previous
+		unchanged.Path = result.Manifest.AddAsset(link, previous.Path)
+		unchanged.ETag, unchanged.LastModified = validators(resp.Header, previous.ETag, previous.LastModified)
+		// 本地样式表已改写，按上次记录的引用继续检查其中的资源
+		for _, ref := range previous.Refs {
+			fetchRef(ref)
 		}
+		return savedAsset{AssetState: unchanged, Unchanged: true}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return savedAsset{}, StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// classify by Content-Type, falling back to sniffing and the URL extension
+	body := bufio.NewReader(resp.Body)
+	head, _ := body.Peek(512)
+	contentType := resp.Header.Get("Content-Type")
+	dirPath, document := classifyAsset(link, contentType, head)
+	if dirPath == "" {
+		fmt.Printf("跳过不支持的资源类型: %s (%s)\n", link, contentType)
+		return savedAsset{}, nil
+	}
+
+	// reserve the announced size up front so a huge file is skipped before downloading
+	reserved := resp.ContentLength
+	if reserved < 0 {
+		reserved = 0
+	}
+	if limits.tooLarge(reserved) {
+		result.addSkip(link, dirPath, fmt.Sprintf("%v: %d 字节", ErrFileTooLarge, reserved))
+		return savedAsset{}, nil
+	}
+	// 更新时新内容替换上次保存的文件，只按大小差额计入文件夹预算，没有替换时旧文件保留
+	replaced := false
+	if previous != nil {
+		restore := limits.replace(previous.Size)
+		defer func() {
+			if !replaced {
+				restore()
+			}
+		}()
+	}
+	budget := limits.budget(dirPath)
+	if err := budget.Reserve(reserved); err != nil {
+		result.addSkip(link, dirPath, fmt.Sprintf("%v: %d 字节", err, reserved))
+		return savedAsset{}, nil
 	}
-}
 
-// sanitizeFilename 清理文件名中的非法字符
-func sanitizeFilename(filename string) string {
-	// 移除查询参数
-	if idx := strings.Index(filename, "?"); idx != -1 {
-		filename = filename[:idx]
+	// record a unique local name before following references so cyclic imports resolve,
+	// a resource saved before keeps its local name
+	localPath := result.Manifest.AddAsset(link, dirPath+"/"+document)
+	// the previous copy stays on disk and in the manifest when the new download fails
+	forget := func() {
+		if previous == nil {
+			result.Manifest.RemoveAsset(link)
+		}
+	}
+
+	// responses without Content-Length are checked while streaming
+	data := limits.limitReader(body)
+	var refs []string
+	if dirPath == "css" || dirPath == "misc" {
+		// rewrite references so they point to the local copies
+		raw, err := ioutil.ReadAll(data)
+		if err != nil {
+			budget.Release(reserved)
+			forget()
+			if limitExceeded(err) {
+				result.addSkip(link, dirPath, err.Error())
+				return savedAsset{}, nil
+			}
+			return savedAsset{}, err
+		}
+		// release the download slot before fetching referenced resources
+		resp.Body.Close()
+		// remember the references, an unchanged stylesheet is not downloaded again next time
+		fetch := func(ref string) string {
+			refs = append(refs, ref)
+			return fetchRef(ref)
+		}
+		if dirPath == "css" {
+			raw = rewriteCSS(raw, link, fetch)
+		} else {
+			raw = rewriteWebManifest(raw, link, fetch)
+		}
+		data = bytes.NewReader(raw)
 	}
 
-	// 移除Windows文件名中的非法字符
-	invalidChars := regexp.MustCompile(`[<>:"/\\|?*]`)
-	filename = invalidChars.ReplaceAllString(filename, "_")
+	hash := sha256.New()
+	size, err := writeFileToPath(project, path.Base(localPath), path.Dir(localPath), io.TeeReader(data, hash), budget, reserved)
+	if err != nil {
+		// the rewriter keeps the original URL of resources that were not saved
+		forget()
+		if limitExceeded(err) {
+			result.addSkip(link, dirPath, err.Error())
+			return savedAsset{}, nil
+		}
+		return savedAsset{}, err
+	}
+	replaced = true
+	return savedAsset{AssetState: AssetState{
+		Path:         localPath,
+		Size:         size,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Refs:         refs,
+	}}, nil
+}
 
-	// 确保文件名不为空
-	if filename == "" {
-		filename = "unnamed_file"
+// setConditionalHeaders 按上次保存的缓存验证信息设置条件请求头
+func setConditionalHeaders(header http.Header, etag string, lastModified string) {
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
 	}
+}
 
-	return filename
+// validators 返回304响应中的缓存验证信息，响应中没有时沿用上次保存的值
+func validators(header http.Header, etag string, lastModified string) (string, string) {
+	if v := header.Get("ETag"); v != "" {
+		etag = v
+	}
+	if v := header.Get("Last-Modified"); v != "" {
+		lastModified = v
+	}
+	return etag, lastModified
 }
 
-func writeFileToPath(projectPath, base, oldFileExt, newFileExt, fileDir string, resp *http.Response) {
-	// 清理文件名
-	base = sanitizeFilename(base)
-	oldFileExt = sanitizeFilename(oldFileExt)
+// classifyAsset returns the project directory and file name for a downloaded resource
+// the Content-Type header decides first, then content sniffing, then the URL extension
+func classifyAsset(link string, contentType string, head []byte) (string, string) {
+	// error pages and other documents are never saved as assets
+	if strings.Contains(strings.ToLower(contentType), "text/html") {
+		return "", ""
+	}
+	_, name := parser.AssetPath(link)
+	urlExt := path.Ext(name)
 
-	var name = base[0 : len(base)-len(oldFileExt)]
-	document := name + newFileExt
+	ext := parser.MIMEExtension(contentType)
+	if ext == "" && len(head) > 0 {
+		ext = parser.MIMEExtension(http.DetectContentType(head))
+	}
+	if ext == "" {
+		ext = urlExt
+	}
 
-	// get the project name and path we use the path to
-	f, err := os.OpenFile(projectPath+"/"+fileDir+"/"+document, os.O_RDWR|os.O_CREATE, 0777)
-	if err != nil {
-		panic(err)
+	dirPath := parser.AssetDir(ext)
+	if dirPath == "" {
+		return "", ""
 	}
-	defer f.Close()
-	htmlData, err := ioutil.ReadAll(resp.Body)
+	// keep the URL's own extension when it names the same kind of file, e.g. ".jpeg"
+	if urlExt != "" && parser.AssetDir(urlExt) == dirPath {
+		ext = urlExt
+	}
+	return dirPath, parser.URLBasename(link) + ext
+}
 
+// relativeAssetPath returns the path of a saved resource relative to another asset directory
+func relativeAssetPath(fromDir, localPath string) string {
+	if path.Dir(localPath) == fromDir {
+		return path.Base(localPath)
+	}
+	return "../" + localPath
+}
+
+// writeFileToPath streams body into the project and counts the written bytes against budget,
+// reserved bytes were already taken from budget by the caller;
+// the file only becomes visible in the storage backend on Commit, so a failed,
+// cancelled or over-budget download never leaves a partial file behind;
+// it returns the number of bytes written
+func writeFileToPath(project file.Storage, document, fileDir string, body io.Reader, budget *file.Budget, reserved int64) (int64, error) {
+	f, err := project.Create(fileDir + "/" + document)
 	if err != nil {
-		panic(err)
+		budget.Release(reserved)
+		return 0, err
+	}
+	w := file.NewBudgetWriter(f, budget, reserved)
+	if _, err := io.Copy(w, body); err != nil {
+		f.Abort()
+		w.Abort()
+		return 0, err
+	}
+	if err := f.Commit(); err != nil {
+		w.Abort()
+		return 0, err
 	}
-	f.Write(htmlData)
+	w.Commit()
+	return w.Written(), nil
 }
@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+func TestExtractAssetReplaceBudget(t *testing.T) {
+	old := strings.Repeat("a", 100)
+	changed := strings.Repeat("b", 120)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail.css" {
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/css")
+		fmt.Fprint(w, changed)
+	}))
+	defer srv.Close()
+
+	tables := []struct {
+		name     string
+		path     string
+		expected string
+		used     int64
+	}{
+		// 旧文件100字节，替换为120字节只需要20字节的剩余空间
+		{"changed", "/site.css", changed, 120},
+		// 下载失败时保留旧文件，预算恢复原值
+		{"failed", "/fail.css", old, 100},
+	}
+	for _, table := range tables {
+		projectPath := t.TempDir()
+		os.MkdirAll(filepath.Join(projectPath, "css"), file.DirMode)
+		os.WriteFile(filepath.Join(projectPath, "css", "site.css"), []byte(old), file.FileMode)
+		link := srv.URL + table.path
+		result := newResult()
+		result.Manifest.AddAsset(link, "css/site.css")
+		budget := file.NewBudget(150, 100)
+		previous := &AssetState{Path: "css/site.css", Size: int64(len(old))}
+
+		_, err := extractAsset(srv.Client(), link, file.NewDirStorage(projectPath), result, newDownloadLimits(budget, SizeLimits{}), previous, func(ref string) string { return "" })
+		data, _ := os.ReadFile(filepath.Join(projectPath, "css", "site.css"))
+		if string(data) != table.expected || budget.Used() != table.used || len(result.Skipped) != 0 {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s ExtractAssetReplaceBudget Failed: %s, expected %d bytes used %d, got %d bytes used %d (%v)\n",
+				red("[-]"), table.name, len(table.expected), table.used, len(data), budget.Used(), err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s ExtractAssetReplaceBudget Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
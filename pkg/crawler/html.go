@@ -1,50 +1,48 @@
 package crawler
 
 import (
-	"crypto/tls"
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
 )
 
-// HTMLExtractorFromResponse 从colly响应中提取HTML内容
-func HTMLExtractorFromResponse(link string, projectPath string, bodyData []byte) {
-	fmt.Println("从响应提取HTML --> ", link)
+// HTMLExtractorFromResponse 从colly响应中提取HTML内容，保存到项目内的pagePath
+func HTMLExtractorFromResponse(link string, projectPath string, pagePath string, bodyData []byte) error {
 	fmt.Println("项目路径 --> ", projectPath)
+	return savePage(link, file.NewDirStorage(projectPath), pagePath, bodyData)
+}
+
+// savePage 将HTML内容保存到后端中项目内的pagePath
+func savePage(link string, project file.Storage, pagePath string, bodyData []byte) error {
+	fmt.Println("从响应提取HTML --> ", link)
 
 	fmt.Printf("HTML内容长度: %d 字节\n", len(bodyData))
 
 	if len(bodyData) == 0 {
-		fmt.Println("警告: HTML内容为空!")
-		return
+		return fmt.Errorf("HTML内容为空")
 	}
 
-	// 创建或打开index.html文件
-	f, err := os.OpenFile(projectPath+"/"+"index.html", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
+	// 写入完成后才替换原页面，子页面所在目录会自动创建
+	written, err := file.WriteStorageFile(project, pagePath, bytes.NewReader(bodyData))
 	if err != nil {
-		fmt.Printf("创建文件失败: %v\n", err)
-		return
-	}
-	defer f.Close()
-
-	written, err := f.Write(bodyData)
-	if err != nil {
-		fmt.Printf("写入文件失败: %v\n", err)
-		return
+		return fmt.Errorf("写入文件失败: %w", err)
 	}
 
 	fmt.Printf("成功写入 %d 字节到文件\n", written)
+	return nil
 }
 
-// HTMLExtractor ...
-func HTMLExtractor(link string, projectPath string) {
+// HTMLExtractor 下载link并保存为项目的index.html，
+// 通过指定的HTTP客户端（通常由NewHTTPClient创建）请求，以便使用配置的代理、cookie、用户代理和重试
+func HTMLExtractor(client *http.Client, link string, projectPath string) {
 	fmt.Println("Extracting --> ", link)
 	fmt.Println("Project path --> ", projectPath)
 
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	// get the html body
-	resp, err := http.Get(link)
+	resp, err := client.Get(link)
 	if err != nil {
 		fmt.Printf("HTTP请求失败: %v\n", err)
 		return
@@ -56,14 +54,6 @@ func HTMLExtractor(link string, projectPath string) {
 	fmt.Printf("HTTP状态码: %d\n", resp.StatusCode)
 	fmt.Printf("Content-Type: %s\n", resp.Header.Get("Content-Type"))
 
-	// get the project name and path we use the path to
-	f, err := os.OpenFile(projectPath+"/"+"index.html", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
-	if err != nil {
-		fmt.Printf("创建文件失败: %v\n", err)
-		return
-	}
-	defer f.Close()
-
 	htmlData, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		fmt.Printf("读取响应内容失败: %v\n", err)
@@ -77,7 +67,8 @@ func HTMLExtractor(link string, projectPath string) {
 		return
 	}
 
-	written, err := f.Write(htmlData)
+	// get the project name and path we use the path to
+	written, err := file.WriteStorageFile(file.NewDirStorage(projectPath), "index.html", bytes.NewReader(htmlData))
 	if err != nil {
 		fmt.Printf("写入文件失败: %v\n", err)
 		return
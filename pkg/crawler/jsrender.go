@@ -0,0 +1,532 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"golang.org/x/net/html"
+)
+
+const (
+	// defaultRenderTimeout 未配置时每个页面执行脚本的最长时间
+	defaultRenderTimeout = 10 * time.Second
+	// renderVirtualTime 定时器最多执行到的虚拟时间（毫秒）
+	renderVirtualTime = 10000
+	// renderMaxTimers 最多执行的定时器回调次数，避免setInterval无限执行
+	renderMaxTimers = 10000
+)
+
+// JSRenderer 使用内置的JavaScript引擎（goja）和最小的DOM实现执行页面中的脚本，不需要浏览器，
+// 适合由脚本构建内容的简单页面：支持DOM操作、document.write、定时器、事件、fetch和XMLHttpRequest（只发送GET请求），
+// 不支持布局、样式计算和ES模块（type="module"的脚本不执行，nomodule的脚本会执行）；
+// 定时器按虚拟时间立即执行，页面中的脚本、fetch和XMLHttpRequest请求的资源都会作为资源下载
+type JSRenderer struct {
+	// Timeout 每个页面执行脚本的最长时间，0表示默认10秒，超时时保存原始HTML
+	Timeout time.Duration
+	// RemoveScripts 渲染后移除页面中的脚本，避免在本地预览时脚本再次构建页面
+	RemoveScripts bool
+}
+
+// Render 实现Renderer接口
+func (r *JSRenderer) Render(ctx context.Context, page RenderPage) (*Rendered, error) {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(bytes.NewReader(page.HTML))
+	if err != nil {
+		return nil, fmt.Errorf("解析HTML失败: %w", err)
+	}
+
+	vm := goja.New()
+	run := &jsRun{vm: vm, page: page, location: base, fetched: make(map[string]*Fetched), responses: make(map[string]*Fetched)}
+	// 相对链接按<base href>解析
+	documentBase := base
+	if b := findNode(doc, func(e *html.Node) bool { return e.Data == "base" && getAttr(e, "href") != "" }); b != nil {
+		if u, err := base.Parse(getAttr(b, "href")); err == nil {
+			documentBase = u
+		}
+	}
+	run.dom = newDOMShim(vm, doc, documentBase)
+	run.dom.inserted = run.inserted
+
+	// 超时或取消时中断正在执行的脚本
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultRenderTimeout
+	}
+	timer := time.AfterFunc(timeout, func() { vm.Interrupt("执行脚本超时") })
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() { vm.Interrupt(ctx.Err()) })
+	defer stop()
+
+	if err := run.setup(); err != nil {
+		return nil, err
+	}
+	// 按文档顺序执行页面中原有的脚本，之后触发DOMContentLoaded和load事件并执行定时器
+	for _, script := range findNodes(doc, isScript) {
+		if !run.dom.done[script] {
+			run.runScript(script)
+		}
+	}
+	run.dom.readyState = "interactive"
+	run.call("__dispatch", run.dom.wrap(doc), vm.ToValue("readystatechange"))
+	run.call("__dispatch", run.dom.wrap(doc), vm.ToValue("DOMContentLoaded"))
+	run.call("__dispatch", vm.GlobalObject(), vm.ToValue("DOMContentLoaded"))
+	run.dom.readyState = "complete"
+	run.call("__dispatch", run.dom.wrap(doc), vm.ToValue("readystatechange"))
+	run.call("__dispatch", vm.GlobalObject(), vm.ToValue("load"))
+	run.call("__runTimers", vm.ToValue(renderVirtualTime), vm.ToValue(renderMaxTimers))
+	if run.err != nil {
+		return nil, run.err
+	}
+
+	if r.RemoveScripts {
+		for _, script := range findNodes(doc, isScript) {
+			if javascriptType(getAttr(script, "type")) && script.Parent != nil {
+				script.Parent.RemoveChild(script)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return nil, err
+	}
+	return &Rendered{HTML: buf.Bytes(), Resources: run.requested, Responses: run.responses}, nil
+}
+
+// jsRun 一次页面渲染的状态
+type jsRun struct {
+	vm       *goja.Runtime
+	dom      *domShim
+	page     RenderPage
+	location *url.URL
+
+	// fetched 已请求的URL及响应，同一URL只请求一次
+	fetched map[string]*Fetched
+	// requested 加载的外部脚本等子资源，按请求顺序
+	requested []string
+	// responses 通过fetch或XMLHttpRequest以GET请求的响应
+	responses map[string]*Fetched
+	// err 脚本被中断（超时或取消）时的错误，之后不再执行脚本
+	err error
+}
+
+// setup 创建window、document、location等全局对象
+func (j *jsRun) setup() error {
+	vm := j.vm
+	location := vm.NewObject()
+	u := j.location
+	origin := u.Scheme + "://" + u.Host
+	search := ""
+	if u.RawQuery != "" {
+		search = "?" + u.RawQuery
+	}
+	hash := ""
+	if u.Fragment != "" {
+		hash = "#" + u.Fragment
+	}
+	for key, value := range map[string]string{
+		"href": u.String(), "protocol": u.Scheme + ":", "host": u.Host, "hostname": u.Hostname(),
+		"port": u.Port(), "pathname": u.EscapedPath(), "search": search, "hash": hash, "origin": origin,
+	} {
+		location.Set(key, value)
+	}
+	vm.Set("location", location)
+	vm.Set("document", j.dom.wrap(j.dom.doc))
+	vm.Set("__nodeProto", j.dom.proto)
+	vm.Set("__fetch", j.jsFetch)
+	vm.Set("__error", func(call goja.FunctionCall) goja.Value {
+		fmt.Printf("脚本执行出错: %s (%s)\n", j.page.URL, call.Argument(0).String())
+		return goja.Undefined()
+	})
+	vm.Set("btoa", func(call goja.FunctionCall) goja.Value {
+		s := call.Argument(0).String()
+		data := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				panic(vm.NewTypeError("The string to be encoded contains characters outside of the Latin1 range."))
+			}
+			data = append(data, byte(r))
+		}
+		return vm.ToValue(base64.StdEncoding.EncodeToString(data))
+	})
+	vm.Set("atob", func(call goja.FunctionCall) goja.Value {
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(call.Argument(0).String(), "="))
+		if err != nil {
+			panic(vm.NewTypeError("The string to be decoded is not correctly encoded."))
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return vm.ToValue(string(runes))
+	})
+	if _, err := vm.RunScript("prelude.js", jsPrelude); err != nil {
+		return fmt.Errorf("初始化脚本环境失败: %w", err)
+	}
+	return nil
+}
+
+// runScript 执行script元素，外部脚本通过Fetch获取，脚本出错时继续执行其他脚本
+func (j *jsRun) runScript(n *html.Node) error {
+	j.dom.done[n] = true
+	if j.err != nil || !javascriptType(getAttr(n, "type")) {
+		return nil
+	}
+	name, code := j.page.URL, textContent(n)
+	if src := getAttr(n, "src"); src != "" {
+		name = j.dom.resolve(src)
+		fetched, err := j.fetch(name, true)
+		if err == nil && (fetched.Status < 200 || fetched.Status > 299) {
+			err = StatusError{StatusCode: fetched.Status}
+		}
+		if err != nil {
+			fmt.Printf("加载脚本失败: %s (%v)\n", name, err)
+			return err
+		}
+		code = string(fetched.Body)
+	}
+
+	previous, before := j.dom.current, j.dom.writeBefore
+	j.dom.current, j.dom.writeBefore = n, n.NextSibling
+	_, err := j.vm.RunScript(name, code)
+	j.dom.current, j.dom.writeBefore = previous, before
+	j.check(err)
+	return nil
+}
+
+// inserted 动态插入文档的脚本（包括document.write写入的）按插入顺序在之后的任务中执行，完成后触发load或error事件
+func (j *jsRun) inserted(n *html.Node) {
+	scripts := findNodes(n, isScript)
+	if isScript(n) {
+		scripts = append([]*html.Node{n}, scripts...)
+	}
+	for _, script := range scripts {
+		if j.dom.done[script] {
+			continue
+		}
+		script := script
+		j.dom.done[script] = true
+		task := func(goja.FunctionCall) goja.Value {
+			event := "load"
+			if err := j.runScript(script); err != nil {
+				event = "error"
+			}
+			j.call("__dispatch", j.dom.wrap(script), j.vm.ToValue(event))
+			return goja.Undefined()
+		}
+		j.call("setTimeout", j.vm.ToValue(task), j.vm.ToValue(0))
+	}
+}
+
+// call 调用全局函数，脚本中的异常在JS中已处理，这里只记录中断
+func (j *jsRun) call(name string, args ...goja.Value) {
+	if j.err != nil {
+		return
+	}
+	fn, ok := goja.AssertFunction(j.vm.Get(name))
+	if !ok {
+		return
+	}
+	_, err := fn(goja.Undefined(), args...)
+	j.check(err)
+}
+
+// check 记录脚本错误，中断时之后不再执行脚本
+func (j *jsRun) check(err error) {
+	if err == nil {
+		return
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if j.err == nil {
+			j.err = fmt.Errorf("渲染中断: %v", interrupted.Value())
+		}
+		return
+	}
+	fmt.Printf("脚本执行出错: %s (%v)\n", j.page.URL, err)
+}
+
+// fetch 通过RenderPage.Fetch请求URL，同一URL只请求一次；resource为true时（外部脚本）记录为需要下载的资源，
+// fetch和XMLHttpRequest请求的接口数据不作为资源下载
+func (j *jsRun) fetch(link string, resource bool) (*Fetched, error) {
+	if j.page.Fetch == nil {
+		return nil, fmt.Errorf("不支持请求资源")
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("不支持的URL: %s", link)
+	}
+	if resource && !slices.Contains(j.requested, link) {
+		j.requested = append(j.requested, link)
+	}
+	if fetched, ok := j.fetched[link]; ok {
+		return fetched, nil
+	}
+	fetched, err := j.page.Fetch(link)
+	if err != nil {
+		return nil, err
+	}
+	j.fetched[link] = fetched
+	return fetched, nil
+}
+
+// jsFetch 实现供fetch和XMLHttpRequest使用的__fetch(method, url)，只发送GET和HEAD请求，
+// 返回{status, url, headers, body}
+func (j *jsRun) jsFetch(call goja.FunctionCall) goja.Value {
+	method := strings.ToUpper(call.Argument(0).String())
+	if method != http.MethodGet && method != http.MethodHead {
+		panic(j.vm.NewTypeError(fmt.Sprintf("渲染时不发送%s请求", method)))
+	}
+	link := j.dom.resolve(call.Argument(1).String())
+	fetched, err := j.fetch(link, false)
+	if err != nil {
+		panic(j.vm.NewTypeError(err.Error()))
+	}
+	headers := j.vm.NewObject()
+	for key, values := range fetched.Header {
+		headers.Set(strings.ToLower(key), strings.Join(values, ", "))
+	}
+	response := j.vm.NewObject()
+	response.Set("status", fetched.Status)
+	response.Set("url", fetched.URL)
+	response.Set("headers", headers)
+	if method == http.MethodHead {
+		response.Set("body", "")
+	} else {
+		j.responses[link] = fetched
+		response.Set("body", string(fetched.Body))
+	}
+	return response
+}
+
+// javascriptType 判断script的type是否是可执行的经典脚本
+func javascriptType(t string) bool {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "", "text/javascript", "application/javascript", "application/x-javascript", "text/ecmascript", "application/ecmascript":
+		return true
+	}
+	return false
+}
+
+// jsPrelude 在页面脚本之前执行，用JS实现window上的定时器、事件、fetch、XMLHttpRequest、存储等接口
+const jsPrelude = `(function (global) {
+	var nodeProto = global.__nodeProto;
+	delete global.__nodeProto;
+	global.window = global.self = global.globalThis = global.top = global.parent = global;
+
+	// 定时器按到期的虚拟时间依次执行，不真正等待
+	var timers = [], timerSeq = 0, now = 0;
+	function addTimer(fn, delay, args, repeat) {
+		delay = Math.max(0, Number(delay) || 0);
+		timers.push({id: ++timerSeq, fn: fn, delay: delay, due: now + delay, args: args, repeat: repeat});
+		return timerSeq;
+	}
+	global.setTimeout = function (fn, delay) { return addTimer(fn, delay, Array.prototype.slice.call(arguments, 2), false); };
+	global.setInterval = function (fn, delay) { return addTimer(fn, delay, Array.prototype.slice.call(arguments, 2), true); };
+	global.clearTimeout = global.clearInterval = function (id) {
+		timers = timers.filter(function (t) { return t.id !== id; });
+	};
+	global.requestAnimationFrame = function (fn) { return addTimer(function () { fn(now); }, 16, [], false); };
+	global.cancelAnimationFrame = global.clearTimeout;
+	global.requestIdleCallback = function (fn) { return addTimer(function () { fn({didTimeout: false, timeRemaining: function () { return 50; }}); }, 1, [], false); };
+	global.cancelIdleCallback = global.clearTimeout;
+	global.queueMicrotask = function (fn) { Promise.resolve().then(fn); };
+	global.performance = {now: function () { return now; }, mark: function () {}, measure: function () {}};
+	global.__runTimers = function (limit, max) {
+		for (var n = 0; timers.length && n < max; n++) {
+			var next = 0;
+			for (var i = 1; i < timers.length; i++) {
+				if (timers[i].due < timers[next].due) next = i;
+			}
+			var t = timers[next];
+			if (t.due > limit) break;
+			now = t.due;
+			if (t.repeat) t.due = now + Math.max(t.delay, 1); else timers.splice(next, 1);
+			if (typeof t.fn !== "function") continue;
+			try { t.fn.apply(global, t.args); } catch (e) { __error(e && e.stack || e); }
+		}
+	};
+
+	// 事件
+	function Event(type, init) {
+		this.type = String(type);
+		this.bubbles = !!(init && init.bubbles);
+		this.cancelable = !!(init && init.cancelable);
+		this.detail = init ? init.detail : undefined;
+		this.defaultPrevented = false;
+		this.timeStamp = now;
+	}
+	Event.prototype.preventDefault = function () { this.defaultPrevented = true; };
+	Event.prototype.stopPropagation = Event.prototype.stopImmediatePropagation = function () {};
+	global.Event = global.CustomEvent = Event;
+	function dispatch(target, event) {
+		if (typeof event === "string") event = new Event(event);
+		if (!event.target) event.target = target;
+		event.currentTarget = target;
+		var listeners = target.__listeners && target.__listeners[event.type] ? target.__listeners[event.type].slice() : [];
+		var handler = target["on" + event.type.toLowerCase()];
+		if (typeof handler === "function") listeners.unshift(handler);
+		for (var i = 0; i < listeners.length; i++) {
+			try {
+				if (typeof listeners[i] === "function") listeners[i].call(target, event); else listeners[i].handleEvent(event);
+			} catch (e) { __error(e && e.stack || e); }
+		}
+		return !event.defaultPrevented;
+	}
+	global.__dispatch = dispatch;
+	var eventTarget = {
+		addEventListener: function (type, fn) {
+			if (!fn) return;
+			if (!Object.prototype.hasOwnProperty.call(this, "__listeners")) Object.defineProperty(this, "__listeners", {value: {}});
+			(this.__listeners[type] = this.__listeners[type] || []).push(fn);
+		},
+		removeEventListener: function (type, fn) {
+			var list = this.__listeners && this.__listeners[type];
+			if (list && list.indexOf(fn) >= 0) list.splice(list.indexOf(fn), 1);
+		},
+		dispatchEvent: function (event) { return dispatch(this, event); }
+	};
+	for (var key in eventTarget) {
+		global[key] = eventTarget[key];
+		nodeProto[key] = eventTarget[key];
+	}
+
+	// DOM构造函数，用于instanceof判断
+	function Node() { throw new TypeError("Illegal constructor"); }
+	Node.prototype = nodeProto;
+	Node.ELEMENT_NODE = 1; Node.TEXT_NODE = 3; Node.COMMENT_NODE = 8; Node.DOCUMENT_NODE = 9; Node.DOCUMENT_FRAGMENT_NODE = 11;
+	global.Node = global.Element = global.HTMLElement = global.Document = global.DocumentFragment = global.Text = Node;
+	document.defaultView = global;
+	document.location = location;
+	location.toString = function () { return this.href; };
+	location.assign = location.replace = location.reload = function () {};
+
+	// fetch和XMLHttpRequest通过__fetch同步获取，结果在之后的任务中返回
+	function Headers(headers) { this._headers = headers || {}; }
+	Headers.prototype.get = function (name) {
+		var value = this._headers[String(name).toLowerCase()];
+		return value === undefined ? null : value;
+	};
+	Headers.prototype.has = function (name) { return this.get(name) !== null; };
+	Headers.prototype.forEach = function (fn) {
+		for (var key in this._headers) fn(this._headers[key], key, this);
+	};
+	function Response(r) {
+		this.status = r.status;
+		this.ok = r.status >= 200 && r.status < 300;
+		this.url = r.url;
+		this.headers = new Headers(r.headers);
+		this._body = r.body;
+	}
+	Response.prototype.text = function () { return Promise.resolve(this._body); };
+	Response.prototype.json = function () {
+		var body = this._body;
+		return new Promise(function (resolve) { resolve(JSON.parse(body)); });
+	};
+	Response.prototype.clone = function () { return this; };
+	global.Headers = Headers;
+	global.Response = Response;
+	global.fetch = function (input, init) {
+		var link = typeof input === "string" ? input : input && input.url !== undefined ? input.url : String(input);
+		var method = init && init.method || "GET";
+		return new Promise(function (resolve) { resolve(new Response(__fetch(method, String(link)))); });
+	};
+
+	function XMLHttpRequest() {
+		this.readyState = 0;
+		this.status = 0;
+		this.responseText = "";
+		this.response = null;
+		this.responseType = "";
+		this._headers = {};
+	}
+	XMLHttpRequest.UNSENT = 0; XMLHttpRequest.OPENED = 1; XMLHttpRequest.DONE = 4;
+	XMLHttpRequest.prototype = {
+		open: function (method, link, async) {
+			this._method = method;
+			this._url = link;
+			this._async = async !== false;
+			this.readyState = 1;
+		},
+		setRequestHeader: function () {},
+		overrideMimeType: function () {},
+		abort: function () {},
+		getResponseHeader: function (name) {
+			var value = this._headers[String(name).toLowerCase()];
+			return value === undefined ? null : value;
+		},
+		getAllResponseHeaders: function () {
+			var lines = [];
+			for (var key in this._headers) lines.push(key + ": " + this._headers[key]);
+			return lines.join("\r\n");
+		},
+		send: function () {
+			var xhr = this;
+			function done() {
+				var failed = false;
+				try {
+					var r = __fetch(xhr._method, xhr._url);
+					xhr.status = r.status;
+					xhr.responseURL = r.url;
+					xhr._headers = r.headers;
+					xhr.responseText = r.body;
+					xhr.response = xhr.responseType === "json" ? JSON.parse(r.body) : r.body;
+				} catch (e) {
+					failed = true;
+				}
+				xhr.readyState = 4;
+				dispatch(xhr, "readystatechange");
+				dispatch(xhr, failed ? "error" : "load");
+				dispatch(xhr, "loadend");
+			}
+			if (this._async) setTimeout(done, 0); else done();
+		}
+	};
+	for (var key in eventTarget) XMLHttpRequest.prototype[key] = eventTarget[key];
+	global.XMLHttpRequest = XMLHttpRequest;
+
+	// 内存中的localStorage和sessionStorage
+	function Storage() {}
+	Storage.prototype = {
+		getItem: function (key) { return Object.prototype.hasOwnProperty.call(this, key) ? this[key] : null; },
+		setItem: function (key, value) { this[key] = String(value); },
+		removeItem: function (key) { delete this[key]; },
+		clear: function () { for (var key in this) if (Object.prototype.hasOwnProperty.call(this, key)) delete this[key]; },
+		key: function (i) { var keys = Object.keys(this); return i < keys.length ? keys[i] : null; }
+	};
+	Object.defineProperty(Storage.prototype, "length", {get: function () { return Object.keys(this).length; }});
+	global.localStorage = new Storage();
+	global.sessionStorage = new Storage();
+
+	// 没有实际效果的浏览器接口
+	function Observer() {}
+	Observer.prototype.observe = Observer.prototype.unobserve = Observer.prototype.disconnect = function () {};
+	Observer.prototype.takeRecords = function () { return []; };
+	global.MutationObserver = global.IntersectionObserver = global.ResizeObserver = Observer;
+	var noop = function () {};
+	global.console = {log: noop, info: noop, warn: noop, error: noop, debug: noop, trace: noop, group: noop, groupEnd: noop, table: noop};
+	global.navigator = {userAgent: "Mozilla/5.0 (compatible; go-website-clone)", language: "en-US", languages: ["en-US"], onLine: true, cookieEnabled: true, platform: ""};
+	global.history = {length: 1, state: null, pushState: noop, replaceState: noop, back: noop, forward: noop, go: noop};
+	global.screen = {width: 1280, height: 800, availWidth: 1280, availHeight: 800};
+	global.innerWidth = 1280; global.innerHeight = 800; global.devicePixelRatio = 1;
+	global.scrollX = global.scrollY = global.pageXOffset = global.pageYOffset = 0;
+	global.alert = global.scrollTo = global.scrollBy = global.focus = global.blur = global.print = noop;
+	global.confirm = function () { return false; };
+	global.prompt = global.open = function () { return null; };
+	global.getComputedStyle = function (el) { return el.style; };
+	global.matchMedia = function (query) {
+		return {matches: false, media: query, onchange: null, addListener: noop, removeListener: noop, addEventListener: noop, removeEventListener: noop};
+	};
+})(this);
+`
@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"errors"
+	"io"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+// ErrFileTooLarge 资源超出单个文件大小限制
+var ErrFileTooLarge = errors.New("超出单个文件大小限制")
+
+// SizeLimits 单个文件和各类资源的大小限制（字节），0表示不限制，
+// 各类配额同时计入MaxFolderSize
+type SizeLimits struct {
+	// MaxFileSize 单个资源的最大大小
+	MaxFileSize int64
+	// Scripts 脚本（js）的总配额
+	Scripts int64
+	// Styles 样式表（css）的总配额
+	Styles int64
+	// Images 图片（imgs）的总配额
+	Images int64
+	// Fonts 字体（fonts）的总配额
+	Fonts int64
+	// Media 音视频（media）的总配额
+	Media int64
+}
+
+// downloadLimits 下载时使用的文件夹预算、各类资源配额和单文件限制，nil表示不限制
+type downloadLimits struct {
+	maxFileSize int64
+	folder      *file.Budget
+	categories  map[string]*file.Budget
+}
+
+// newDownloadLimits 为每类资源创建计入文件夹预算的子预算
+func newDownloadLimits(folder *file.Budget, limits SizeLimits) *downloadLimits {
+	return &downloadLimits{
+		maxFileSize: limits.MaxFileSize,
+		folder:      folder,
+		categories: map[string]*file.Budget{
+			"js":    folder.Sub(limits.Scripts),
+			"css":   folder.Sub(limits.Styles),
+			"imgs":  folder.Sub(limits.Images),
+			"fonts": folder.Sub(limits.Fonts),
+			"media": folder.Sub(limits.Media),
+		},
+	}
+}
+
+// budget 返回资源类型对应的预算，没有配额的类型（如页面和misc）只计入文件夹预算
+func (l *downloadLimits) budget(category string) *file.Budget {
+	if l == nil {
+		return nil
+	}
+	if budget, ok := l.categories[category]; ok {
+		return budget
+	}
+	return l.folder
+}
+
+// free 项目中已有的文件被删除后从文件夹预算中归还其大小，各类配额只统计本次下载的内容，不受影响
+func (l *downloadLimits) free(size int64) {
+	if l != nil {
+		l.folder.Release(size)
+	}
+}
+
+// replace 新内容将替换项目中已有的size字节，先归还旧文件的大小，使替换只按差额计入文件夹预算；
+// 返回的函数在没有替换（下载失败或被跳过，旧文件保留）时重新计入旧文件的大小
+func (l *downloadLimits) replace(size int64) func() {
+	l.free(size)
+	return func() {
+		if l != nil {
+			l.folder.Add(size)
+		}
+	}
+}
+
+// tooLarge 判断已知大小的资源是否超出单个文件大小限制
+func (l *downloadLimits) tooLarge(size int64) bool {
+	return l != nil && l.maxFileSize > 0 && size > l.maxFileSize
+}
+
+// limitReader 读取超过单个文件大小限制时返回ErrFileTooLarge
+func (l *downloadLimits) limitReader(r io.Reader) io.Reader {
+	if l == nil || l.maxFileSize <= 0 {
+		return r
+	}
+	return &fileSizeReader{r: r, remaining: l.maxFileSize}
+}
+
+type fileSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (r *fileSizeReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	// 多读一个字节以判断是否超出限制
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// limitExceeded 判断错误是否由大小限制引起，这类资源记为跳过而不是失败
+func limitExceeded(err error) bool {
+	return errors.Is(err, file.ErrBudgetExceeded) || errors.Is(err, file.ErrQuotaExceeded) || errors.Is(err, ErrFileTooLarge)
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Renderer 在保存页面前处理下载的HTML，例如执行脚本得到客户端构建的DOM；
+// 返回的HTML代替原始响应保存并用于查找资源和链接，可以被多个goroutine同时调用
+type Renderer interface {
+	Render(ctx context.Context, page RenderPage) (*Rendered, error)
+}
+
+// RenderPage 需要渲染的页面
+type RenderPage struct {
+	// URL 页面的最终URL，用于解析相对链接
+	URL string
+	// HTML 下载的原始HTML
+	HTML []byte
+	// Fetch 通过克隆使用的HTTP客户端获取页面引用的脚本和接口数据，不会保存到项目中
+	Fetch func(link string) (*Fetched, error)
+}
+
+// Fetched 渲染时请求的资源的响应
+type Fetched struct {
+	// URL 重定向后的最终URL
+	URL    string
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Rendered 渲染结果
+type Rendered struct {
+	// HTML 渲染后的页面
+	HTML []byte
+	// Resources 渲染过程中加载的外部脚本等子资源的绝对URL，会像页面中的资源一样下载到项目中；
+	// fetch和XMLHttpRequest请求的接口数据不包括在内
+	Resources []string
+	// Responses 页面脚本通过fetch或XMLHttpRequest请求的接口响应，以请求的绝对URL为键，
+	// 开启接口记录时保存到项目中供本地预览返回
+	Responses map[string]*Fetched
+}
+
+// PassThroughRenderer 不做任何处理，原样返回下载的HTML
+type PassThroughRenderer struct{}
+
+// Render 实现Renderer接口
+func (PassThroughRenderer) Render(ctx context.Context, page RenderPage) (*Rendered, error) {
+	return &Rendered{HTML: page.HTML}, nil
+}
+
+// renderFetch 返回渲染时使用的Fetch函数，请求经过共享客户端（代理、cookie、调度和重试），
+// 响应体受单个文件大小限制
+func renderFetch(ctx context.Context, client *http.Client, limits *downloadLimits) func(link string) (*Fetched, error) {
+	return func(link string) (*Fetched, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(limits.limitReader(resp.Body))
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", link, err)
+		}
+		return &Fetched{URL: resp.Request.URL.String(), Status: resp.StatusCode, Header: resp.Header, Body: body}, nil
+	}
+}
@@ -0,0 +1,1069 @@
+package crawler
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dop251/goja"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// domShim 在goja中以最小的DOM接口暴露解析后的HTML文档，JS中的节点对象与html.Node一一对应，
+// 所有节点共用一个原型，只实现由脚本构建页面常用的属性和方法，没有布局和样式计算
+type domShim struct {
+	vm   *goja.Runtime
+	doc  *html.Node
+	base *url.URL
+
+	proto   *goja.Object
+	objects map[*html.Node]*goja.Object
+	nodes   map[*goja.Object]*html.Node
+	// fragments createDocumentFragment创建的节点，插入时移动其子节点
+	fragments map[*html.Node]bool
+	// done 已执行或不应执行（如innerHTML插入）的脚本
+	done map[*html.Node]bool
+
+	// current 正在执行的脚本，document.write的内容插入在writeBefore之前
+	current     *html.Node
+	writeBefore *html.Node
+	readyState  string
+	cookie      string
+	// inserted 节点插入文档后调用，用于执行动态插入的脚本
+	inserted func(n *html.Node)
+}
+
+// newDOMShim 为文档创建DOM对象原型，base用于解析src和href属性
+func newDOMShim(vm *goja.Runtime, doc *html.Node, base *url.URL) *domShim {
+	d := &domShim{
+		vm:         vm,
+		doc:        doc,
+		base:       base,
+		proto:      vm.NewObject(),
+		objects:    make(map[*html.Node]*goja.Object),
+		nodes:      make(map[*goja.Object]*html.Node),
+		fragments:  make(map[*html.Node]bool),
+		done:       make(map[*html.Node]bool),
+		readyState: "loading",
+	}
+	d.defineNode()
+	d.defineElement()
+	d.defineDocument()
+	return d
+}
+
+// wrap 返回节点对应的JS对象，同一节点总是返回同一对象
+func (d *domShim) wrap(n *html.Node) goja.Value {
+	if n == nil {
+		return goja.Null()
+	}
+	if obj, ok := d.objects[n]; ok {
+		return obj
+	}
+	obj := d.vm.NewObject()
+	obj.SetPrototype(d.proto)
+	d.objects[n] = obj
+	d.nodes[obj] = n
+	return obj
+}
+
+// node 返回JS对象对应的节点，不是DOM节点时返回nil
+func (d *domShim) node(v goja.Value) *html.Node {
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	return d.nodes[obj]
+}
+
+// list 将节点列表转换为JS数组
+func (d *domShim) list(nodes []*html.Node) goja.Value {
+	items := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		items[i] = d.wrap(n)
+	}
+	return d.vm.NewArray(items...)
+}
+
+// throw 抛出JS异常
+func (d *domShim) throw(msg string) {
+	panic(d.vm.NewTypeError(msg))
+}
+
+// method 在原型上定义方法，this必须是DOM节点
+func (d *domShim) method(name string, fn func(n *html.Node, call goja.FunctionCall) goja.Value) {
+	d.proto.Set(name, func(call goja.FunctionCall) goja.Value {
+		n := d.node(call.This)
+		if n == nil {
+			d.throw("Illegal invocation")
+		}
+		return fn(n, call)
+	})
+}
+
+// accessor 在原型上定义属性，set为nil时只读
+func (d *domShim) accessor(name string, get func(n *html.Node) goja.Value, set func(n *html.Node, v goja.Value)) {
+	getter := d.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		n := d.node(call.This)
+		if n == nil {
+			return goja.Undefined()
+		}
+		return get(n)
+	})
+	var setter goja.Value
+	if set != nil {
+		setter = d.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if n := d.node(call.This); n != nil {
+				set(n, call.Argument(0))
+			}
+			return goja.Undefined()
+		})
+	}
+	d.proto.DefineAccessorProperty(name, getter, setter, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// defineNode 定义所有节点共有的属性和方法
+func (d *domShim) defineNode() {
+	d.accessor("nodeType", func(n *html.Node) goja.Value {
+		switch {
+		case d.fragments[n]:
+			return d.vm.ToValue(11)
+		case n.Type == html.ElementNode:
+			return d.vm.ToValue(1)
+		case n.Type == html.TextNode:
+			return d.vm.ToValue(3)
+		case n.Type == html.CommentNode:
+			return d.vm.ToValue(8)
+		}
+		return d.vm.ToValue(9)
+	}, nil)
+	d.accessor("nodeName", func(n *html.Node) goja.Value {
+		switch {
+		case d.fragments[n]:
+			return d.vm.ToValue("#document-fragment")
+		case n.Type == html.ElementNode:
+			return d.vm.ToValue(strings.ToUpper(n.Data))
+		case n.Type == html.TextNode:
+			return d.vm.ToValue("#text")
+		case n.Type == html.CommentNode:
+			return d.vm.ToValue("#comment")
+		}
+		return d.vm.ToValue("#document")
+	}, nil)
+	d.accessor("ownerDocument", func(n *html.Node) goja.Value { return d.wrap(d.doc) }, nil)
+	d.accessor("parentNode", func(n *html.Node) goja.Value { return d.wrap(n.Parent) }, nil)
+	d.accessor("parentElement", func(n *html.Node) goja.Value { return d.wrap(elementOrNil(n.Parent)) }, nil)
+	d.accessor("firstChild", func(n *html.Node) goja.Value { return d.wrap(n.FirstChild) }, nil)
+	d.accessor("lastChild", func(n *html.Node) goja.Value { return d.wrap(n.LastChild) }, nil)
+	d.accessor("nextSibling", func(n *html.Node) goja.Value { return d.wrap(n.NextSibling) }, nil)
+	d.accessor("previousSibling", func(n *html.Node) goja.Value { return d.wrap(n.PrevSibling) }, nil)
+	d.accessor("childNodes", func(n *html.Node) goja.Value { return d.list(childNodes(n, false)) }, nil)
+	d.accessor("children", func(n *html.Node) goja.Value { return d.list(childNodes(n, true)) }, nil)
+	d.accessor("childElementCount", func(n *html.Node) goja.Value { return d.vm.ToValue(len(childNodes(n, true))) }, nil)
+	d.accessor("firstElementChild", func(n *html.Node) goja.Value { return d.wrap(nextElement(n.FirstChild)) }, nil)
+	d.accessor("lastElementChild", func(n *html.Node) goja.Value { return d.wrap(prevElement(n.LastChild)) }, nil)
+	d.accessor("nextElementSibling", func(n *html.Node) goja.Value { return d.wrap(nextElement(n.NextSibling)) }, nil)
+	d.accessor("previousElementSibling", func(n *html.Node) goja.Value { return d.wrap(prevElement(n.PrevSibling)) }, nil)
+	d.accessor("isConnected", func(n *html.Node) goja.Value { return d.vm.ToValue(d.connected(n)) }, nil)
+
+	data := func(n *html.Node) goja.Value {
+		if n.Type == html.TextNode || n.Type == html.CommentNode {
+			return d.vm.ToValue(n.Data)
+		}
+		return goja.Null()
+	}
+	setData := func(n *html.Node, v goja.Value) {
+		if n.Type == html.TextNode || n.Type == html.CommentNode {
+			n.Data = v.String()
+		}
+	}
+	d.accessor("nodeValue", data, setData)
+	d.accessor("data", data, setData)
+
+	text := func(n *html.Node) goja.Value {
+		if n.Type == html.DocumentNode && !d.fragments[n] {
+			return goja.Null()
+		}
+		return d.vm.ToValue(textContent(n))
+	}
+	setText := func(n *html.Node, v goja.Value) {
+		if n.Type == html.TextNode || n.Type == html.CommentNode {
+			n.Data = v.String()
+			return
+		}
+		removeChildren(n)
+		if s := v.String(); s != "" {
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: s})
+		}
+	}
+	d.accessor("textContent", text, setText)
+	d.accessor("innerText", text, setText)
+
+	d.method("appendChild", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		d.insert(n, d.node(call.Argument(0)), nil)
+		return call.Argument(0)
+	})
+	d.method("insertBefore", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		d.insert(n, d.node(call.Argument(0)), d.node(call.Argument(1)))
+		return call.Argument(0)
+	})
+	d.method("removeChild", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		child := d.node(call.Argument(0))
+		if child == nil || child.Parent != n {
+			d.throw("The node to be removed is not a child of this node")
+		}
+		n.RemoveChild(child)
+		return call.Argument(0)
+	})
+	d.method("replaceChild", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		old := d.node(call.Argument(1))
+		if old == nil || old.Parent != n {
+			d.throw("The node to be replaced is not a child of this node")
+		}
+		if d.node(call.Argument(0)) == old {
+			return call.Argument(1)
+		}
+		d.insert(n, d.node(call.Argument(0)), old)
+		n.RemoveChild(old)
+		return call.Argument(1)
+	})
+	d.method("remove", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+		return goja.Undefined()
+	})
+	d.method("append", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		for _, arg := range call.Arguments {
+			d.insert(n, d.nodeOrText(arg), nil)
+		}
+		return goja.Undefined()
+	})
+	d.method("prepend", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		first := n.FirstChild
+		for _, arg := range call.Arguments {
+			d.insert(n, d.nodeOrText(arg), first)
+		}
+		return goja.Undefined()
+	})
+	d.method("before", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		for _, arg := range call.Arguments {
+			if n.Parent != nil {
+				d.insert(n.Parent, d.nodeOrText(arg), n)
+			}
+		}
+		return goja.Undefined()
+	})
+	d.method("after", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		next := n.NextSibling
+		for _, arg := range call.Arguments {
+			if n.Parent != nil {
+				d.insert(n.Parent, d.nodeOrText(arg), next)
+			}
+		}
+		return goja.Undefined()
+	})
+	d.method("cloneNode", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		clone := cloneNode(n, call.Argument(0).ToBoolean())
+		if d.fragments[n] {
+			d.fragments[clone] = true
+		}
+		return d.wrap(clone)
+	})
+	d.method("contains", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		for other := d.node(call.Argument(0)); other != nil; other = other.Parent {
+			if other == n {
+				return d.vm.ToValue(true)
+			}
+		}
+		return d.vm.ToValue(false)
+	})
+	d.method("hasChildNodes", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.vm.ToValue(n.FirstChild != nil)
+	})
+
+	d.method("querySelector", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		found := goquery.NewDocumentFromNode(n).Find(call.Argument(0).String())
+		if found.Length() == 0 {
+			return goja.Null()
+		}
+		return d.wrap(found.Nodes[0])
+	})
+	d.method("querySelectorAll", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.list(goquery.NewDocumentFromNode(n).Find(call.Argument(0).String()).Nodes)
+	})
+	d.method("getElementById", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		id := call.Argument(0).String()
+		return d.wrap(findNode(n, func(e *html.Node) bool { return getAttr(e, "id") == id }))
+	})
+	d.method("getElementsByTagName", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		tag := strings.ToLower(call.Argument(0).String())
+		return d.list(findNodes(n, func(e *html.Node) bool { return tag == "*" || e.Data == tag }))
+	})
+	d.method("getElementsByClassName", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		names := strings.Fields(call.Argument(0).String())
+		return d.list(findNodes(n, func(e *html.Node) bool {
+			classes := strings.Fields(getAttr(e, "class"))
+			for _, name := range names {
+				if !containsString(classes, name) {
+					return false
+				}
+			}
+			return len(names) > 0
+		}))
+	})
+}
+
+// defineElement 定义元素的属性和方法
+func (d *domShim) defineElement() {
+	d.accessor("tagName", func(n *html.Node) goja.Value {
+		if n.Type != html.ElementNode {
+			return goja.Undefined()
+		}
+		return d.vm.ToValue(strings.ToUpper(n.Data))
+	}, nil)
+	d.accessor("localName", func(n *html.Node) goja.Value {
+		if n.Type != html.ElementNode {
+			return goja.Undefined()
+		}
+		return d.vm.ToValue(n.Data)
+	}, nil)
+
+	// 字符串属性直接对应HTML属性，src和href返回绝对URL
+	attrs := map[string]string{
+		"id": "id", "className": "class", "type": "type", "rel": "rel", "name": "name", "alt": "alt",
+		"content": "content", "action": "action", "method": "method", "placeholder": "placeholder",
+		"lang": "lang", "dir": "dir", "target": "target", "charset": "charset", "width": "width",
+		"height": "height", "htmlFor": "for", "role": "role",
+	}
+	for prop, attr := range attrs {
+		attr := attr
+		d.accessor(prop, func(n *html.Node) goja.Value {
+			if n.Type != html.ElementNode {
+				return goja.Undefined()
+			}
+			return d.vm.ToValue(getAttr(n, attr))
+		}, func(n *html.Node, v goja.Value) {
+			setAttr(n, attr, v.String())
+		})
+	}
+	for _, attr := range []string{"src", "href"} {
+		attr := attr
+		d.accessor(attr, func(n *html.Node) goja.Value {
+			if n.Type != html.ElementNode {
+				return goja.Undefined()
+			}
+			return d.vm.ToValue(d.resolve(getAttr(n, attr)))
+		}, func(n *html.Node, v goja.Value) {
+			setAttr(n, attr, v.String())
+		})
+	}
+	for _, attr := range []string{"checked", "disabled", "hidden", "selected", "async", "defer", "required", "multiple"} {
+		attr := attr
+		d.accessor(attr, func(n *html.Node) goja.Value {
+			_, ok := lookupAttr(n, attr)
+			return d.vm.ToValue(ok)
+		}, func(n *html.Node, v goja.Value) {
+			if v.ToBoolean() {
+				setAttr(n, attr, "")
+			} else {
+				removeAttr(n, attr)
+			}
+		})
+	}
+	// textarea的值是其文本内容，其他元素对应value属性
+	d.accessor("value", func(n *html.Node) goja.Value {
+		if n.Data == "textarea" {
+			return d.vm.ToValue(textContent(n))
+		}
+		return d.vm.ToValue(getAttr(n, "value"))
+	}, func(n *html.Node, v goja.Value) {
+		if n.Data == "textarea" {
+			removeChildren(n)
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: v.String()})
+			return
+		}
+		setAttr(n, "value", v.String())
+	})
+
+	d.accessor("innerHTML", func(n *html.Node) goja.Value {
+		var buf bytes.Buffer
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			html.Render(&buf, c)
+		}
+		return d.vm.ToValue(buf.String())
+	}, func(n *html.Node, v goja.Value) {
+		if n.Type != html.ElementNode && !d.fragments[n] {
+			return
+		}
+		removeChildren(n)
+		for _, c := range d.parseFragment(v.String(), n) {
+			d.insert(n, c, nil)
+		}
+	})
+	d.accessor("outerHTML", func(n *html.Node) goja.Value {
+		var buf bytes.Buffer
+		html.Render(&buf, n)
+		return d.vm.ToValue(buf.String())
+	}, nil)
+	d.method("insertAdjacentHTML", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		parent, before := n, (*html.Node)(nil)
+		switch strings.ToLower(call.Argument(0).String()) {
+		case "beforebegin":
+			parent, before = n.Parent, n
+		case "afterbegin":
+			before = n.FirstChild
+		case "afterend":
+			parent, before = n.Parent, n.NextSibling
+		}
+		if parent == nil {
+			return goja.Undefined()
+		}
+		for _, c := range d.parseFragment(call.Argument(1).String(), parent) {
+			d.insert(parent, c, before)
+		}
+		return goja.Undefined()
+	})
+
+	d.method("getAttribute", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		if v, ok := lookupAttr(n, strings.ToLower(call.Argument(0).String())); ok {
+			return d.vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+	d.method("setAttribute", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		setAttr(n, strings.ToLower(call.Argument(0).String()), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	d.method("removeAttribute", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		removeAttr(n, strings.ToLower(call.Argument(0).String()))
+		return goja.Undefined()
+	})
+	d.method("hasAttribute", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		_, ok := lookupAttr(n, strings.ToLower(call.Argument(0).String()))
+		return d.vm.ToValue(ok)
+	})
+	d.method("matches", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.vm.ToValue(goquery.NewDocumentFromNode(n).Is(call.Argument(0).String()))
+	})
+	d.method("closest", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		found := goquery.NewDocumentFromNode(n).Closest(call.Argument(0).String())
+		if found.Length() == 0 {
+			return goja.Null()
+		}
+		return d.wrap(found.Nodes[0])
+	})
+	d.method("getBoundingClientRect", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		rect := d.vm.NewObject()
+		for _, key := range []string{"x", "y", "top", "left", "right", "bottom", "width", "height"} {
+			rect.Set(key, 0)
+		}
+		return rect
+	})
+	for _, name := range []string{"focus", "blur", "click", "scrollIntoView"} {
+		d.method(name, func(n *html.Node, call goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+
+	d.accessor("style", func(n *html.Node) goja.Value {
+		return d.vm.NewDynamicObject(&styleDeclaration{vm: d.vm, n: n})
+	}, func(n *html.Node, v goja.Value) {
+		setAttr(n, "style", v.String())
+	})
+	d.accessor("dataset", func(n *html.Node) goja.Value {
+		return d.vm.NewDynamicObject(&dataset{vm: d.vm, n: n})
+	}, nil)
+	d.accessor("classList", func(n *html.Node) goja.Value {
+		return d.classList(n)
+	}, nil)
+}
+
+// defineDocument 定义document的属性和方法
+func (d *domShim) defineDocument() {
+	d.accessor("documentElement", func(n *html.Node) goja.Value { return d.wrap(d.documentElement()) }, nil)
+	d.accessor("head", func(n *html.Node) goja.Value { return d.wrap(d.child("head")) }, nil)
+	d.accessor("body", func(n *html.Node) goja.Value { return d.wrap(d.child("body")) }, nil)
+	d.accessor("currentScript", func(n *html.Node) goja.Value { return d.wrap(d.current) }, nil)
+	d.accessor("readyState", func(n *html.Node) goja.Value { return d.vm.ToValue(d.readyState) }, nil)
+	d.accessor("cookie", func(n *html.Node) goja.Value { return d.vm.ToValue(d.cookie) }, func(n *html.Node, v goja.Value) {
+		d.cookie = v.String()
+	})
+	// document.title是title元素的文本，元素的title是属性
+	d.accessor("title", func(n *html.Node) goja.Value {
+		if n != d.doc {
+			return d.vm.ToValue(getAttr(n, "title"))
+		}
+		return d.vm.ToValue(strings.TrimSpace(textContent(findNode(d.doc, func(e *html.Node) bool { return e.Data == "title" }))))
+	}, func(n *html.Node, v goja.Value) {
+		if n != d.doc {
+			setAttr(n, "title", v.String())
+			return
+		}
+		title := findNode(d.doc, func(e *html.Node) bool { return e.Data == "title" })
+		if title == nil {
+			title = newElement("title")
+			if head := d.child("head"); head != nil {
+				head.AppendChild(title)
+			}
+		}
+		removeChildren(title)
+		title.AppendChild(&html.Node{Type: html.TextNode, Data: v.String()})
+	})
+
+	d.method("createElement", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.wrap(newElement(strings.ToLower(call.Argument(0).String())))
+	})
+	d.method("createElementNS", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		e := newElement(call.Argument(1).String())
+		if strings.HasSuffix(call.Argument(0).String(), "/svg") {
+			e.Namespace = "svg"
+		}
+		return d.wrap(e)
+	})
+	d.method("createTextNode", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.wrap(&html.Node{Type: html.TextNode, Data: call.Argument(0).String()})
+	})
+	d.method("createComment", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		return d.wrap(&html.Node{Type: html.CommentNode, Data: call.Argument(0).String()})
+	})
+	d.method("createDocumentFragment", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		fragment := &html.Node{Type: html.DocumentNode}
+		d.fragments[fragment] = true
+		return d.wrap(fragment)
+	})
+	write := func(n *html.Node, call goja.FunctionCall) goja.Value {
+		var markup strings.Builder
+		for _, arg := range call.Arguments {
+			markup.WriteString(arg.String())
+		}
+		d.write(markup.String())
+		return goja.Undefined()
+	}
+	d.method("write", write)
+	d.method("writeln", func(n *html.Node, call goja.FunctionCall) goja.Value {
+		write(n, call)
+		d.write("\n")
+		return goja.Undefined()
+	})
+}
+
+// insert 将child插入parent中before之前（before为nil时追加到末尾），文档片段插入其所有子节点
+func (d *domShim) insert(parent *html.Node, child *html.Node, before *html.Node) {
+	if parent == nil || child == nil {
+		d.throw("parameter is not of type 'Node'")
+	}
+	if d.fragments[child] {
+		for c := child.FirstChild; c != nil; {
+			next := c.NextSibling
+			d.insert(parent, c, before)
+			c = next
+		}
+		return
+	}
+	if before == child {
+		before = child.NextSibling
+	}
+	if before != nil && before.Parent != parent {
+		d.throw("The node before which the new node is to be inserted is not a child of this node")
+	}
+	for p := parent; p != nil; p = p.Parent {
+		if p == child {
+			d.throw("The new child element contains the parent")
+		}
+	}
+	if child.Parent != nil {
+		child.Parent.RemoveChild(child)
+	}
+	parent.InsertBefore(child, before)
+	if d.inserted != nil && d.connected(child) {
+		d.inserted(child)
+	}
+}
+
+// nodeOrText 返回参数对应的节点，字符串转换为文本节点
+func (d *domShim) nodeOrText(v goja.Value) *html.Node {
+	if n := d.node(v); n != nil {
+		return n
+	}
+	return &html.Node{Type: html.TextNode, Data: v.String()}
+}
+
+// parseFragment 以context为上下文解析HTML片段，其中的脚本不会执行
+func (d *domShim) parseFragment(markup string, context *html.Node) []*html.Node {
+	if context.Type != html.ElementNode {
+		context = d.child("body")
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(markup), context)
+	if err != nil {
+		return nil
+	}
+	for _, n := range nodes {
+		for _, script := range findNodes(n, isScript) {
+			d.done[script] = true
+		}
+		if isScript(n) {
+			d.done[n] = true
+		}
+	}
+	return nodes
+}
+
+// write 实现document.write：解析时插入在当前脚本之后，其中的脚本同动态插入的脚本一样执行；
+// 页面加载完成后追加到body末尾
+func (d *domShim) write(markup string) {
+	parent, before := d.child("body"), (*html.Node)(nil)
+	if d.current != nil && d.current.Parent != nil && d.readyState == "loading" {
+		parent, before = d.current.Parent, d.writeBefore
+		if before != nil && before.Parent != parent {
+			before = nil
+		}
+	}
+	if parent == nil {
+		return
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(markup), elementOr(parent, d.child("body")))
+	if err != nil {
+		return
+	}
+	for _, n := range nodes {
+		d.insert(parent, n, before)
+	}
+}
+
+// resolve 返回属性值对应的绝对URL，无法解析时原样返回
+func (d *domShim) resolve(ref string) string {
+	if ref == "" || d.base == nil {
+		return ref
+	}
+	u, err := d.base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// connected 判断节点是否在文档中
+func (d *domShim) connected(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == d.doc {
+			return true
+		}
+	}
+	return false
+}
+
+// documentElement 返回html元素
+func (d *domShim) documentElement() *html.Node {
+	return nextElement(d.doc.FirstChild)
+}
+
+// child 返回html元素下指定名称的子元素（head或body）
+func (d *domShim) child(name string) *html.Node {
+	root := d.documentElement()
+	if root == nil {
+		return nil
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// classList 返回操作class属性的对象
+func (d *domShim) classList(n *html.Node) goja.Value {
+	classes := func() []string { return strings.Fields(getAttr(n, "class")) }
+	set := func(list []string) { setAttr(n, "class", strings.Join(list, " ")) }
+	obj := d.vm.NewObject()
+	obj.Set("add", func(call goja.FunctionCall) goja.Value {
+		list := classes()
+		for _, arg := range call.Arguments {
+			if !containsString(list, arg.String()) {
+				list = append(list, arg.String())
+			}
+		}
+		set(list)
+		return goja.Undefined()
+	})
+	obj.Set("remove", func(call goja.FunctionCall) goja.Value {
+		var list []string
+		for _, name := range classes() {
+			removed := false
+			for _, arg := range call.Arguments {
+				removed = removed || arg.String() == name
+			}
+			if !removed {
+				list = append(list, name)
+			}
+		}
+		set(list)
+		return goja.Undefined()
+	})
+	obj.Set("contains", func(call goja.FunctionCall) goja.Value {
+		return d.vm.ToValue(containsString(classes(), call.Argument(0).String()))
+	})
+	obj.Set("toggle", func(call goja.FunctionCall) goja.Value {
+		name := call.Argument(0).String()
+		list := classes()
+		has := containsString(list, name)
+		want := !has
+		if !goja.IsUndefined(call.Argument(1)) {
+			want = call.Argument(1).ToBoolean()
+		}
+		switch {
+		case want && !has:
+			set(append(list, name))
+		case !want && has:
+			var kept []string
+			for _, c := range list {
+				if c != name {
+					kept = append(kept, c)
+				}
+			}
+			set(kept)
+		}
+		return d.vm.ToValue(want)
+	})
+	obj.Set("length", len(classes()))
+	return obj
+}
+
+// styleDeclaration 以对象形式读写元素的style属性，属性名使用驼峰形式（如backgroundColor）
+type styleDeclaration struct {
+	vm *goja.Runtime
+	n  *html.Node
+}
+
+// declarations 按顺序返回style属性中的声明
+func (s *styleDeclaration) declarations() [][2]string {
+	var list [][2]string
+	for _, decl := range strings.Split(getAttr(s.n, "style"), ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if ok && strings.TrimSpace(name) != "" {
+			list = append(list, [2]string{strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)})
+		}
+	}
+	return list
+}
+
+// setProperty 设置或删除（value为空时）一条声明并写回style属性
+func (s *styleDeclaration) setProperty(name string, value string) {
+	var parts []string
+	found := false
+	for _, decl := range s.declarations() {
+		if decl[0] == name {
+			found = true
+			if value == "" {
+				continue
+			}
+			decl[1] = value
+		}
+		parts = append(parts, decl[0]+": "+decl[1])
+	}
+	if !found && value != "" {
+		parts = append(parts, name+": "+value)
+	}
+	if len(parts) == 0 {
+		removeAttr(s.n, "style")
+		return
+	}
+	setAttr(s.n, "style", strings.Join(parts, "; ")+";")
+}
+
+func (s *styleDeclaration) property(name string) (string, bool) {
+	for _, decl := range s.declarations() {
+		if decl[0] == name {
+			return decl[1], true
+		}
+	}
+	return "", false
+}
+
+func (s *styleDeclaration) Get(key string) goja.Value {
+	switch key {
+	case "cssText":
+		return s.vm.ToValue(getAttr(s.n, "style"))
+	case "setProperty":
+		return s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			s.setProperty(strings.ToLower(call.Argument(0).String()), call.Argument(1).String())
+			return goja.Undefined()
+		})
+	case "removeProperty":
+		return s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			s.setProperty(strings.ToLower(call.Argument(0).String()), "")
+			return goja.Undefined()
+		})
+	case "getPropertyValue":
+		return s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			value, _ := s.property(strings.ToLower(call.Argument(0).String()))
+			return s.vm.ToValue(value)
+		})
+	}
+	if value, ok := s.property(cssName(key)); ok {
+		return s.vm.ToValue(value)
+	}
+	return nil
+}
+
+func (s *styleDeclaration) Set(key string, val goja.Value) bool {
+	if key == "cssText" {
+		setAttr(s.n, "style", val.String())
+		return true
+	}
+	value := ""
+	if !goja.IsNull(val) && !goja.IsUndefined(val) {
+		value = val.String()
+	}
+	s.setProperty(cssName(key), value)
+	return true
+}
+
+func (s *styleDeclaration) Has(key string) bool {
+	_, ok := s.property(cssName(key))
+	return ok
+}
+
+func (s *styleDeclaration) Delete(key string) bool {
+	s.setProperty(cssName(key), "")
+	return true
+}
+
+func (s *styleDeclaration) Keys() []string {
+	var keys []string
+	for _, decl := range s.declarations() {
+		keys = append(keys, camelName(decl[0]))
+	}
+	return keys
+}
+
+// dataset 以对象形式读写元素的data-*属性
+type dataset struct {
+	vm *goja.Runtime
+	n  *html.Node
+}
+
+func (s *dataset) Get(key string) goja.Value {
+	if value, ok := lookupAttr(s.n, "data-"+cssName(key)); ok {
+		return s.vm.ToValue(value)
+	}
+	return nil
+}
+
+func (s *dataset) Set(key string, val goja.Value) bool {
+	setAttr(s.n, "data-"+cssName(key), val.String())
+	return true
+}
+
+func (s *dataset) Has(key string) bool {
+	_, ok := lookupAttr(s.n, "data-"+cssName(key))
+	return ok
+}
+
+func (s *dataset) Delete(key string) bool {
+	removeAttr(s.n, "data-"+cssName(key))
+	return true
+}
+
+func (s *dataset) Keys() []string {
+	var keys []string
+	for _, attr := range s.n.Attr {
+		if name, ok := strings.CutPrefix(attr.Key, "data-"); ok {
+			keys = append(keys, camelName(name))
+		}
+	}
+	return keys
+}
+
+// cssName 将驼峰形式的属性名转换为连字符形式，如backgroundColor转换为background-color
+func cssName(key string) string {
+	if key == "cssFloat" {
+		return "float"
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('-')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// camelName 将连字符形式的属性名转换为驼峰形式
+func camelName(name string) string {
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// newElement 创建HTML元素节点
+func newElement(tag string) *html.Node {
+	return &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+}
+
+// cloneNode 复制节点，deep为true时同时复制所有子节点
+func cloneNode(n *html.Node, deep bool) *html.Node {
+	clone := &html.Node{Type: n.Type, Data: n.Data, DataAtom: n.DataAtom, Namespace: n.Namespace}
+	clone.Attr = append([]html.Attribute(nil), n.Attr...)
+	if deep {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			clone.AppendChild(cloneNode(c, true))
+		}
+	}
+	return clone
+}
+
+// removeChildren 删除节点的所有子节点
+func removeChildren(n *html.Node) {
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+}
+
+// textContent 返回节点中所有文本节点的内容
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode || n.Type == html.CommentNode {
+		return n.Data
+	}
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				b.WriteString(c.Data)
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// childNodes 返回子节点，elements为true时只返回元素
+func childNodes(n *html.Node, elements bool) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !elements || c.Type == html.ElementNode {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// findNodes 按文档顺序返回n的后代中满足条件的元素
+func findNodes(n *html.Node, match func(e *html.Node) bool) []*html.Node {
+	var nodes []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && match(c) {
+				nodes = append(nodes, c)
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return nodes
+}
+
+// findNode 返回n的后代中第一个满足条件的元素
+func findNode(n *html.Node, match func(e *html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := findNode(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// isScript 判断节点是否是script元素
+func isScript(n *html.Node) bool {
+	return n.Type == html.ElementNode && n.Data == "script"
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for ; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for ; n != nil; n = n.PrevSibling {
+		if n.Type == html.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
+
+func elementOrNil(n *html.Node) *html.Node {
+	if n != nil && n.Type == html.ElementNode {
+		return n
+	}
+	return nil
+}
+
+func elementOr(n *html.Node, fallback *html.Node) *html.Node {
+	if n != nil && n.Type == html.ElementNode {
+		return n
+	}
+	return fallback
+}
+
+func lookupAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func getAttr(n *html.Node, key string) string {
+	value, _ := lookupAttr(n, key)
+	return value
+}
+
+func setAttr(n *html.Node, key string, value string) {
+	for i, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+func removeAttr(n *html.Node, key string) {
+	for i, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestJSRenderer(t *testing.T) {
+	// 渲染时可以请求的资源
+	remote := map[string]string{
+		"https://example.com/js/app.js":     `document.getElementById("app").innerHTML = "<h1>" + document.title + "</h1>";`,
+		"https://example.com/api/items":     `["a","b"]`,
+		"https://example.com/js/loader.js":  `window.loaded = true;`,
+		"https://example.com/api/xhr.json":  `{"name":"xhr"}`,
+		"https://example.com/js/missing.js": "",
+	}
+	fetch := func(link string) (*Fetched, error) {
+		body, ok := remote[link]
+		status := http.StatusOK
+		if !ok || body == "" {
+			status = http.StatusNotFound
+		}
+		return &Fetched{URL: link, Status: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(body)}, nil
+	}
+
+	tables := []struct {
+		name     string
+		page     string
+		expected []string
+	}{
+		{"external script", `<title>Shop</title><div id="app"></div><script src="/js/app.js"></script>`,
+			[]string{`<div id="app"><h1>Shop</h1></div>`}},
+		{"create elements", `<ul id="list"></ul><script>
+			var list = document.querySelector("#list");
+			["x", "y"].forEach(function (name) {
+				var li = document.createElement("li");
+				li.className = "item";
+				li.textContent = name;
+				list.appendChild(li);
+			});
+			list.firstElementChild.classList.add("first");
+			list.lastElementChild.style.color = "red";
+		</script>`, []string{`<li class="item first">x</li><li class="item" style="color: red;">y</li>`}},
+		{"document.write", `<p id="a"></p><script>document.write("<b>one</b>"); document.write("<i>two</i>");</script><p id="b"></p>`,
+			[]string{`</script><b>one</b><i>two</i><p id="b">`}},
+		{"fetch and timers", `<div id="out"></div><script>
+			document.addEventListener("DOMContentLoaded", function () {
+				fetch("/api/items").then(function (r) { return r.json(); }).then(function (items) {
+					setTimeout(function () { document.getElementById("out").textContent = items.join(","); }, 500);
+				});
+			});
+		</script>`, []string{`<div id="out">a,b</div>`}},
+		{"xhr and dynamic script", `<span id="x"></span><script>
+			var xhr = new XMLHttpRequest();
+			xhr.open("GET", "api/xhr.json");
+			xhr.responseType = "json";
+			xhr.onload = function () { document.getElementById("x").textContent = xhr.response.name; };
+			xhr.send();
+			var s = document.createElement("script");
+			s.src = "/js/loader.js";
+			s.onload = function () { document.body.setAttribute("data-loaded", String(window.loaded)); };
+			document.head.appendChild(s);
+		</script>`, []string{`<span id="x">xhr</span>`, `<body data-loaded="true">`}},
+		{"errors do not stop rendering", `<script src="/js/missing.js"></script><script>undefinedFunction();</script><script type="module">document.title = "module";</script>
+			<script>document.body.appendChild(document.createTextNode("still running"))</script>`,
+			[]string{`still running`}},
+	}
+	for _, table := range tables {
+		rendered, err := (&JSRenderer{}).Render(context.Background(), RenderPage{URL: "https://example.com/", HTML: []byte(table.page), Fetch: fetch})
+		result := ""
+		if rendered != nil {
+			result = string(rendered.HTML)
+		}
+		missing := ""
+		for _, expected := range table.expected {
+			if !strings.Contains(result, expected) {
+				missing = expected
+			}
+		}
+		if err != nil || missing != "" {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s JSRenderer Failed: %s, expected %s got %s (%v)\n", red("[-]"), table.name, missing, result, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s JSRenderer Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+
+	// 加载过的脚本按顺序返回，接口响应只在Responses中返回，脚本可以选择在渲染后移除
+	rendered, err := (&JSRenderer{RemoveScripts: true}).Render(context.Background(), RenderPage{
+		URL:   "https://example.com/",
+		HTML:  []byte(`<div id="app"></div><script src="/js/app.js"></script><script>fetch("/api/items")</script><script type="application/ld+json">{}</script>`),
+		Fetch: fetch,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources := strings.Join(rendered.Resources, ","); resources != "https://example.com/js/app.js" {
+		t.Errorf("unexpected resources %s", resources)
+	}
+	if _, ok := rendered.Responses["https://example.com/api/items"]; !ok || len(rendered.Responses) != 1 {
+		t.Errorf("unexpected responses %v", rendered.Responses)
+	}
+	if html := string(rendered.HTML); strings.Count(html, "<script") != 1 || !strings.Contains(html, "ld+json") {
+		t.Errorf("scripts not removed: %s", html)
+	}
+
+	// 死循环在超时后中断
+	_, err = (&JSRenderer{Timeout: 100 * time.Millisecond}).Render(context.Background(), RenderPage{
+		URL:  "https://example.com/",
+		HTML: []byte(`<script>while (true) {}</script>`),
+	})
+	if err == nil {
+		t.Error("expected timeout error")
+	}
+}
@@ -0,0 +1,216 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/html"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+	"github.com/z-bool/go-website-clone/pkg/warc"
+)
+
+// archivedResponse WARC中某个URL的响应，响应体保存在临时文件中
+type archivedResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// ReplayWARC 将WARC文件中记录的响应按克隆时的目录结构写入projectPath并重构链接：
+// 第一个HTML页面保存为index.html，其他页面和资源的路径与在线克隆相同，样式表引用的资源同样从归档中读取；
+// 被截断的响应不会还原，同一URL有多个响应时以最后一个成功的为准
+func ReplayWARC(warcPath string, projectPath string) (*Result, error) {
+	f, err := os.Open(warcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	spoolDir, err := os.MkdirTemp("", "warc-replay-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(spoolDir)
+
+	responses, order, err := indexWARC(f, spoolDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取WARC文件失败: %w", err)
+	}
+	fmt.Printf("WARC中共有 %d 个URL\n", len(order))
+
+	// 重定向前的URL作为目标页面的别名，页面间的链接同样可以改写
+	aliases := make(map[string][]string)
+	for _, link := range order {
+		resp := responses[link]
+		if resp.status < 300 || resp.status > 399 {
+			continue
+		}
+		base, _ := url.Parse(link)
+		if target, err := base.Parse(resp.header.Get("Location")); err == nil && resp.header.Get("Location") != "" {
+			key := parser.NormalizeURL(target.String())
+			aliases[key] = append(aliases[key], link)
+		}
+	}
+
+	result := newResult()
+	start := true
+	for _, link := range order {
+		resp := responses[link]
+		if resp.status < 200 || resp.status > 299 || !strings.Contains(strings.ToLower(resp.header.Get("Content-Type")), "text/html") {
+			continue
+		}
+		pagePath := "index.html"
+		if !start {
+			pagePath = parser.PagePath(link)
+		}
+		start = false
+		pagePath = result.Manifest.AddPage(link, pagePath, aliases[link]...)
+		body, err := os.ReadFile(resp.body)
+		if err == nil {
+			err = HTMLExtractorFromResponse(link, projectPath, pagePath, body)
+		}
+		if err != nil {
+			result.addFailure(link, err)
+		}
+	}
+
+	// 资源通过从归档读取响应的客户端按在线克隆的方式保存
+	client := &http.Client{Transport: replayTransport(responses)}
+	project := file.NewDirStorage(projectPath)
+	seen := make(map[string]bool)
+	var fetch func(ref string) string
+	fetch = func(ref string) string {
+		key := parser.NormalizeURL(ref)
+		if seen[key] {
+			localPath, _ := result.Manifest.Asset(ref)
+			return localPath
+		}
+		seen[key] = true
+		saved, err := extractAsset(client, ref, project, result, nil, nil, fetch)
+		if err != nil {
+			result.addFailure(ref, err)
+		}
+		return saved.Path
+	}
+	for _, link := range order {
+		resp := responses[link]
+		if resp.status >= 200 && resp.status <= 299 && !strings.Contains(strings.ToLower(resp.header.Get("Content-Type")), "text/html") {
+			fetch(link)
+		}
+	}
+
+	fmt.Printf("共还原 %d 个页面, %d 个资源\n", len(result.Manifest.PagePaths()), len(result.Manifest.Assets))
+	if err := result.Manifest.Save(projectPath); err != nil {
+		return nil, fmt.Errorf("保存%s失败: %w", file.ManifestName, err)
+	}
+	if err := html.LinkRestructure(projectPath); err != nil {
+		return nil, fmt.Errorf("重构HTML链接失败: %w", err)
+	}
+	return result, nil
+}
+
+// indexWARC 读取所有完整的response记录，响应体写入spoolDir，返回URL到响应的映射和URL首次出现的顺序
+func indexWARC(r io.Reader, spoolDir string) (map[string]*archivedResponse, []string, error) {
+	reader, err := warc.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	responses := make(map[string]*archivedResponse)
+	var order []string
+	for i := 0; ; i++ {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return responses, order, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if record.Type() != warc.TypeResponse || record.Header.Get(warc.FieldTruncated) != "" {
+			continue
+		}
+		link := parser.NormalizeURL(record.TargetURI())
+		resp, err := http.ReadResponse(bufio.NewReader(record.Content), nil)
+		if err != nil {
+			fmt.Printf("跳过无法解析的响应: %s (%v)\n", link, err)
+			continue
+		}
+		var payload io.Reader = resp.Body
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			// 保存的是解压后的内容
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				resp.Body.Close()
+				fmt.Printf("跳过无法解压的响应: %s (%v)\n", link, err)
+				continue
+			}
+			payload = gz
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+		}
+		body := filepath.Join(spoolDir, strconv.Itoa(i))
+		_, err = file.WriteFileAtomic(body, payload)
+		resp.Body.Close()
+		if err != nil {
+			fmt.Printf("跳过不完整的响应: %s (%v)\n", link, err)
+			continue
+		}
+
+		existing, ok := responses[link]
+		if !ok {
+			order = append(order, link)
+		} else if existing.status >= 200 && existing.status <= 299 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+			// 成功的响应不会被之后失败的请求覆盖
+			continue
+		}
+		responses[link] = &archivedResponse{status: resp.StatusCode, header: resp.Header, body: body}
+	}
+}
+
+// replayTransport 从归档中返回响应的传输层，归档中没有的URL返回404
+type replayTransport map[string]*archivedResponse
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	archived, ok := t[parser.NormalizeURL(req.URL.String())]
+	if !ok {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+	body, err := os.Open(archived.body)
+	if err != nil {
+		return nil, err
+	}
+	info, err := body.Stat()
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode:    archived.status,
+		Status:        strconv.Itoa(archived.status) + " " + http.StatusText(archived.status),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        archived.header,
+		Body:          body,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
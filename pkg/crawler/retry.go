@@ -0,0 +1,199 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultMaxAttempts 未配置时每个请求的最大尝试次数
+	defaultMaxAttempts = 3
+	// defaultRetryBaseDelay 未配置时第一次重试前的等待时间
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	// defaultRetryMaxDelay 未配置时两次尝试之间的最长等待时间
+	defaultRetryMaxDelay = 30 * time.Second
+)
+
+// RetryConfig 请求失败后的重试策略
+type RetryConfig struct {
+	// MaxAttempts 每个请求的最大尝试次数（包括首次），0表示使用默认值3，1表示不重试
+	MaxAttempts int
+	// BaseDelay 第一次重试前的等待时间，之后每次翻倍，0表示使用默认值500ms
+	BaseDelay time.Duration
+	// MaxDelay 两次尝试之间的最长等待时间（包括Retry-After），0表示使用默认值30s
+	MaxDelay time.Duration
+}
+
+// withDefaults 返回补全默认值后的配置
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = defaultRetryBaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultRetryMaxDelay
+	}
+	if c.BaseDelay > c.MaxDelay {
+		c.BaseDelay = c.MaxDelay
+	}
+	return c
+}
+
+// backoff 返回第attempt次尝试失败后的等待时间，指数增长并带随机抖动
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay
+	for i := 1; i < attempt && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	// 在[delay/2, delay]之间随机，避免多个请求同时重试
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryableStatus 判断状态码是否为可重试的临时错误
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError 判断请求错误是否可能是临时的，域名不存在时不重试
+func retryableError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	return true
+}
+
+// retryAfter 解析Retry-After响应头，支持秒数和HTTP日期
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryTransport 对网络错误、超时、429和5xx响应按策略重试，
+// timeout限制每次尝试（包括读取响应体）的时间
+type retryTransport struct {
+	config    RetryConfig
+	timeout   time.Duration
+	transport http.RoundTripper
+	// onDone 请求结束后报告URL及实际尝试次数
+	onDone func(link string, attempts int)
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 只重试没有请求体或可以重建请求体的幂等请求
+	maxAttempts := t.config.MaxAttempts
+	if (req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+		(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		maxAttempts = 1
+	}
+
+	link := req.URL.String()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req, attempt)
+
+		retry := attempt < maxAttempts && req.Context().Err() == nil &&
+			((err != nil && retryableError(err)) || (err == nil && retryableStatus(resp.StatusCode)))
+		if !retry {
+			if t.onDone != nil {
+				t.onDone(link, attempt)
+			}
+			return resp, err
+		}
+
+		delay := t.config.backoff(attempt)
+		if resp != nil {
+			// 服务器要求的等待时间优先，但不超过MaxDelay
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = d
+				if delay > t.config.MaxDelay {
+					delay = t.config.MaxDelay
+				}
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err != nil {
+			fmt.Printf("请求失败，%v 后重试(%d/%d): %s (%v)\n", delay, attempt, maxAttempts, link, err)
+		} else {
+			fmt.Printf("HTTP状态码 %d，%v 后重试(%d/%d): %s\n", resp.StatusCode, delay, attempt, maxAttempts, link)
+		}
+		if err := sleepContext(req.Context(), delay); err != nil {
+			if t.onDone != nil {
+				t.onDone(link, attempt)
+			}
+			return nil, err
+		}
+	}
+}
+
+// attempt 发起一次请求，第二次及以后的尝试重建请求体
+func (t retryTransport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	if t.timeout <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			return nil, fmt.Errorf("请求超时(%v): %w", t.timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody 在响应体关闭时释放单次尝试的超时context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tables := []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 31 Dec 2023 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, table := range tables {
+		delay, ok := retryAfter(table.header, now)
+		if delay != table.expected || ok != table.ok {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s RetryAfter Failed: %q, expected %v %v got %v %v\n", red("[-]"), table.header, table.expected, table.ok, delay, ok)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s RetryAfter Passing: %q \n", green("[+]"), table.header)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	config := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}.withDefaults()
+	tables := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{60, time.Second},
+	}
+	for _, table := range tables {
+		delay := config.backoff(table.attempt)
+		// 抖动后的等待时间在[max/2, max]之间
+		if delay < table.max/2 || delay > table.max {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Backoff Failed: attempt %d, expected <= %v got %v\n", red("[-]"), table.attempt, table.max, delay)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Backoff Passing: attempt %d \n", green("[+]"), table.attempt)
+		}
+	}
+}
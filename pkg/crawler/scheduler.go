@@ -0,0 +1,202 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultConcurrency 未配置时全局同时进行的请求数
+const defaultConcurrency = 16
+
+// DownloadConfig 下载调度配置
+type DownloadConfig struct {
+	// Concurrency 全局同时进行的请求数，0表示使用默认值16
+	Concurrency int
+	// PerHostConcurrency 每个主机同时进行的请求数，0表示只受全局限制
+	PerHostConcurrency int
+	// RequestsPerSecond 每个主机每秒最多发起的请求数，0表示不限制
+	RequestsPerSecond float64
+	// RandomDelay 每个请求发起前额外等待的最大随机时间
+	RandomDelay time.Duration
+}
+
+// Scheduler 调度页面和资源的下载，限制全局和每个主机的并发数与请求速率。
+// 限制在HTTP传输层生效，请求从发出到响应体关闭期间占用名额
+type Scheduler struct {
+	ctx    context.Context
+	config DownloadConfig
+	global chan struct{}
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// hostLimiter 单个主机的并发名额和请求间隔
+type hostLimiter struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewScheduler 创建下载调度器，ctx取消后等待中的请求会立即返回
+func NewScheduler(ctx context.Context, config DownloadConfig) *Scheduler {
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	return &Scheduler{
+		ctx:    ctx,
+		config: config,
+		global: make(chan struct{}, config.Concurrency),
+		hosts:  make(map[string]*hostLimiter),
+	}
+}
+
+// Go 在后台执行下载任务，任务中的请求受调度器限制
+func (s *Scheduler) Go(task func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		task()
+	}()
+}
+
+// Wait 等待所有后台任务完成
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Transport 返回受调度器限制的传输层
+func (s *Scheduler) Transport(next http.RoundTripper) http.RoundTripper {
+	return schedulerTransport{scheduler: s, transport: next}
+}
+
+// acquire 依次获取主机名额、等待请求间隔和随机延迟，最后获取全局名额，
+// 返回的release可以安全地多次调用
+func (s *Scheduler) acquire(host string) (func(), error) {
+	h := s.host(host)
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+	releaseHost := func() {
+		if h.slots != nil {
+			<-h.slots
+		}
+	}
+
+	if err := sleepContext(s.ctx, h.reserve(s.config.RequestsPerSecond)); err != nil {
+		releaseHost()
+		return nil, err
+	}
+	if s.config.RandomDelay > 0 {
+		if err := sleepContext(s.ctx, time.Duration(rand.Int63n(int64(s.config.RandomDelay)))); err != nil {
+			releaseHost()
+			return nil, err
+		}
+	}
+
+	select {
+	case s.global <- struct{}{}:
+	case <-s.ctx.Done():
+		releaseHost()
+		return nil, s.ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-s.global
+			releaseHost()
+		})
+	}, nil
+}
+
+func (s *Scheduler) host(host string) *hostLimiter {
+	host = strings.ToLower(host)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hosts[host]
+	if !ok {
+		h = &hostLimiter{}
+		if s.config.PerHostConcurrency > 0 {
+			h.slots = make(chan struct{}, s.config.PerHostConcurrency)
+		}
+		s.hosts[host] = h
+	}
+	return h
+}
+
+// reserve 预约下一个请求时间，返回需要等待的时长
+func (h *hostLimiter) reserve(requestsPerSecond float64) time.Duration {
+	if requestsPerSecond <= 0 {
+		return 0
+	}
+	interval := time.Duration(float64(time.Second) / requestsPerSecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	at := h.next
+	if at.Before(now) {
+		at = now
+	}
+	h.next = at.Add(interval)
+	return at.Sub(now)
+}
+
+// sleepContext 等待指定时间，ctx取消时提前返回错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type schedulerTransport struct {
+	scheduler *Scheduler
+	transport http.RoundTripper
+}
+
+func (t schedulerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.scheduler.acquire(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody 在响应体关闭时归还调度名额
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
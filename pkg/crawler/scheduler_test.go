@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+	tables := []struct {
+		rps      float64
+		requests int
+		expected time.Duration
+	}{
+		{0, 5, 0},
+		{10, 1, 0},
+		{10, 3, 200 * time.Millisecond},
+		{4, 5, time.Second},
+	}
+	for _, table := range tables {
+		h := &hostLimiter{}
+		var wait time.Duration
+		for i := 0; i < table.requests; i++ {
+			wait = h.reserve(table.rps)
+		}
+		// reserve读取当前时间，允许少量误差
+		if wait > table.expected || wait < table.expected-10*time.Millisecond {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Reserve Failed: %v rps x%d, expected %v got %v\n", red("[-]"), table.rps, table.requests, table.expected, wait)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Reserve Passing: %v rps x%d \n", green("[+]"), table.rps, table.requests)
+		}
+	}
+}
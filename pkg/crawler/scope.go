@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// ScopeConfig 爬取范围配置
+type ScopeConfig struct {
+	// AllowedDomains 除起始域名外允许抓取页面的域名
+	AllowedDomains []string
+	// AssetDomains 允许下载资源的域名，为空表示不限制（如第三方CDN）
+	AssetDomains []string
+	// IncludeSubdomains 是否同时允许上述域名的子域名
+	IncludeSubdomains bool
+	// IncludePatterns 页面的路径和查询字符串需要匹配的规则，为空表示全部允许；资源只按AssetDomains过滤
+	IncludePatterns []string
+	// ExcludePatterns 页面的路径和查询字符串命中后跳过的规则
+	ExcludePatterns []string
+}
+
+// matcher 匹配URL的路径和查询字符串
+type matcher interface {
+	Match(string) bool
+}
+
+// domainRule 单个域名规则
+type domainRule struct {
+	name       string
+	subdomains bool
+}
+
+// Scope 判断页面和资源是否在爬取范围内
+type Scope struct {
+	pageDomains  []domainRule
+	assetDomains []domainRule
+	include      []matcher
+	exclude      []matcher
+}
+
+// NewScope 根据起始URL和范围配置创建Scope
+// 域名以"*."开头时总是包含子域名；路径规则以"re:"开头时按正则表达式处理，
+// 否则按glob处理（"*"不跨越"/"，"**"可跨越）
+func NewScope(startURL *url.URL, config ScopeConfig) (*Scope, error) {
+	s := &Scope{
+		pageDomains: []domainRule{newDomainRule(startURL.Hostname(), config.IncludeSubdomains)},
+	}
+	for _, d := range config.AllowedDomains {
+		s.pageDomains = append(s.pageDomains, newDomainRule(d, config.IncludeSubdomains))
+	}
+	if len(config.AssetDomains) > 0 {
+		s.assetDomains = append(s.assetDomains, s.pageDomains...)
+		for _, d := range config.AssetDomains {
+			s.assetDomains = append(s.assetDomains, newDomainRule(d, config.IncludeSubdomains))
+		}
+	}
+
+	var err error
+	if s.include, err = compilePatterns(config.IncludePatterns); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = compilePatterns(config.ExcludePatterns); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AllowPage 判断链接是否可以作为页面抓取
+func (s *Scope) AllowPage(u *url.URL) bool {
+	return matchDomain(s.pageDomains, u.Hostname()) && s.matchPath(u)
+}
+
+// AllowAsset 判断资源是否可以下载，路径规则只用于页面，页面引用的样式、脚本等资源不受其限制
+func (s *Scope) AllowAsset(u *url.URL) bool {
+	return len(s.assetDomains) == 0 || matchDomain(s.assetDomains, u.Hostname())
+}
+
+func matchDomain(rules []domainRule, host string) bool {
+	host = strings.ToLower(host)
+	for _, r := range rules {
+		if host == r.name {
+			return true
+		}
+		if r.subdomains && strings.HasSuffix(host, "."+r.name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) matchPath(u *url.URL) bool {
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	for _, m := range s.exclude {
+		if m.Match(target) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, m := range s.include {
+		if m.Match(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// regexpMatcher 适配正则表达式
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) Match(s string) bool {
+	return m.re.MatchString(s)
+}
+
+func compilePatterns(patterns []string) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(patterns))
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "re:") {
+			re, err := regexp.Compile(strings.TrimPrefix(p, "re:"))
+			if err != nil {
+				return nil, fmt.Errorf("无效的正则规则 %q: %w", p, err)
+			}
+			matchers = append(matchers, regexpMatcher{re: re})
+			continue
+		}
+		g, err := glob.Compile(p, '/')
+		if err != nil {
+			return nil, fmt.Errorf("无效的glob规则 %q: %w", p, err)
+		}
+		matchers = append(matchers, g)
+	}
+	return matchers, nil
+}
+
+// newDomainRule 去除域名中的协议和端口，"*."或"."前缀表示包含子域名
+func newDomainRule(domain string, subdomains bool) domainRule {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if u, err := url.Parse(domain); err == nil && u.Host != "" {
+		domain = u.Hostname()
+	}
+	if strings.HasPrefix(domain, "*.") || strings.HasPrefix(domain, ".") {
+		domain = strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+		subdomains = true
+	}
+	return domainRule{name: domain, subdomains: subdomains}
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestScope(t *testing.T) {
+	start, _ := url.Parse("https://example.com/")
+	scope, err := NewScope(start, ScopeConfig{
+		AllowedDomains:  []string{"*.docs.example.com"},
+		AssetDomains:    []string{"cdn.jsdelivr.net"},
+		IncludePatterns: []string{"/**", "/"},
+		ExcludePatterns: []string{"/admin/**", "re:[?&]sort="},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		url      string
+		asset    bool
+		expected bool
+	}{
+		{"https://example.com/", false, true},
+		{"https://example.com/about", false, true},
+		{"https://EXAMPLE.com/about", false, true},
+		{"https://api.docs.example.com/v1", false, true},
+		{"https://blog.example.com/post", false, false},
+		{"https://cdn.jsdelivr.net/npm/a.js", false, false},
+		{"https://cdn.jsdelivr.net/npm/a.js", true, true},
+		{"https://fonts.gstatic.com/a.woff2", true, false},
+		{"https://example.com/admin/users", false, false},
+		{"https://example.com/list?page=2&sort=asc", false, false},
+		{"https://example.com/list?page=2", false, true},
+		// 路径规则不限制资源
+		{"https://example.com/admin/static/site.css", true, true},
+	}
+	for _, table := range tables {
+		u, _ := url.Parse(table.url)
+		result := scope.AllowPage(u)
+		if table.asset {
+			result = scope.AllowAsset(u)
+		}
+		if result != table.expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Scope Failed: %s (asset %t), expected %t got %t \n", red("[-]"), table.url, table.asset, table.expected, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Scope Passing: %s \n", green("[+]"), table.url)
+		}
+	}
+
+	// 只抓取/blog下的页面时，其他路径和CDN上的资源仍然下载
+	blog, err := NewScope(start, ScopeConfig{AssetDomains: []string{"cdn.jsdelivr.net"}, IncludePatterns: []string{"/blog/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for link, expected := range map[string]bool{
+		"https://example.com/blog/post": true,
+		"https://example.com/about":     false,
+	} {
+		u, _ := url.Parse(link)
+		if blog.AllowPage(u) != expected {
+			t.Errorf("AllowPage(%s) expected %t", link, expected)
+		}
+	}
+	for _, link := range []string{"https://example.com/static/site.css", "https://example.com/js/app.js", "https://cdn.jsdelivr.net/npm/a.css"} {
+		u, _ := url.Parse(link)
+		if !blog.AllowAsset(u) {
+			t.Errorf("asset %s outside include pattern was rejected", link)
+		}
+	}
+}
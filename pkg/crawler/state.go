@@ -0,0 +1,369 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+// StateName 项目中保存爬取进度的文件名
+const StateName = "crawl_state.json"
+
+// stateSaveInterval 爬取过程中保存进度的最短间隔
+const stateSaveInterval = 2 * time.Second
+
+// PageState 已保存页面的本地路径、深度、内容哈希和缓存验证信息，
+// Links和Assets为页面中的同站链接和资源，页面未修改时据此继续抓取
+type PageState struct {
+	Path         string   `json:"path"`
+	Depth        int      `json:"depth"`
+	Aliases      []string `json:"aliases,omitempty"`
+	SHA256       string   `json:"sha256,omitempty"`
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Links        []string `json:"links,omitempty"`
+	Assets       []string `json:"assets,omitempty"`
+}
+
+// AssetState 已完成下载的资源的本地路径、大小、内容哈希和缓存验证信息，
+// Refs为样式表等文件中引用的资源
+type AssetState struct {
+	Path         string   `json:"path"`
+	Size         int64    `json:"size"`
+	SHA256       string   `json:"sha256,omitempty"`
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Refs         []string `json:"refs,omitempty"`
+}
+
+// State 爬取进度，保存在项目目录中，用于中断后继续克隆
+type State struct {
+	// StartURL 起始页面，与本次克隆的URL不同时不能继续
+	StartURL string `json:"start_url"`
+	// Complete 上次爬取是否已全部完成（队列和待下载资源均为空）
+	Complete bool `json:"complete"`
+	// Pages 已保存的页面
+	Pages map[string]PageState `json:"pages"`
+	// Queue 已发现但尚未保存的页面及其深度，包括请求失败的页面
+	Queue map[string]int `json:"queue"`
+	// Assets 已完成下载的资源
+	Assets map[string]AssetState `json:"assets"`
+	// PendingAssets 已发现但尚未完成下载的资源及其类型，包括下载失败的资源
+	PendingAssets map[string]string `json:"pending_assets"`
+
+	mu    sync.Mutex
+	dirty bool
+}
+
+// NewState 为起始页面创建空的爬取进度
+func NewState(startURL string) *State {
+	return &State{
+		StartURL:      parser.NormalizeURL(startURL),
+		Pages:         make(map[string]PageState),
+		Queue:         make(map[string]int),
+		Assets:        make(map[string]AssetState),
+		PendingAssets: make(map[string]string),
+	}
+}
+
+// LoadState 读取项目中的爬取进度，文件不存在时返回os.ErrNotExist
+func LoadState(projectPath string) (*State, error) {
+	return LoadStateFrom(file.NewDirStorage(projectPath))
+}
+
+// LoadStateFrom 读取后端中项目的爬取进度，文件不存在时返回fs.ErrNotExist
+func LoadStateFrom(project file.Storage) (*State, error) {
+	data, err := file.ReadStorageFile(project, StateName)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewState("")
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("解析%s失败: %w", StateName, err)
+	}
+	if s.Pages == nil {
+		s.Pages = make(map[string]PageState)
+	}
+	if s.Queue == nil {
+		s.Queue = make(map[string]int)
+	}
+	if s.Assets == nil {
+		s.Assets = make(map[string]AssetState)
+	}
+	if s.PendingAssets == nil {
+		s.PendingAssets = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Save 将进度保存为项目中的crawl_state.json
+func (s *State) Save(projectPath string) error {
+	return s.SaveTo(file.NewDirStorage(projectPath))
+}
+
+// SaveTo 将进度保存为后端中项目的crawl_state.json
+func (s *State) SaveTo(project file.Storage) error {
+	s.mu.Lock()
+	s.Complete = len(s.Queue) == 0 && len(s.PendingAssets) == 0
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteStorageFile(project, StateName, bytes.NewReader(data))
+	return err
+}
+
+// saveEvery 每隔interval保存一次有变化的进度，直到stop被关闭，
+// 进程被强制结束时最多丢失一个间隔内的进度
+func (s *State) saveEvery(project file.Storage, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			dirty := s.dirty
+			s.mu.Unlock()
+			if dirty {
+				if err := s.SaveTo(project); err != nil {
+					fmt.Printf("保存爬取进度失败: %v\n", err)
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Manifest 按已完成的页面和资源重建资源清单，未完成的下载不会出现在清单中
+func (s *State) Manifest() *file.Manifest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := file.NewManifest()
+	for pageURL, page := range s.Pages {
+		m.AddPage(pageURL, page.Path, page.Aliases...)
+	}
+	for link, asset := range s.Assets {
+		m.AddAsset(link, asset.Path)
+	}
+	return m
+}
+
+// queuePage 将页面加入队列，已保存或已在队列中时返回false
+func (s *State) queuePage(pageURL string, depth int) bool {
+	key := parser.NormalizeURL(pageURL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, saved := s.Pages[key]; saved {
+		return false
+	}
+	if _, queued := s.Queue[key]; queued {
+		return false
+	}
+	s.Queue[key] = depth
+	s.dirty = true
+	return true
+}
+
+// pageDepth 返回队列中页面的深度
+func (s *State) pageDepth(pageURL string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	depth, ok := s.Queue[parser.NormalizeURL(pageURL)]
+	return depth, ok
+}
+
+// dropPage 将页面移出队列，用于不会再保存的页面（如非HTML内容或超出大小限制）
+func (s *State) dropPage(pageURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Queue, parser.NormalizeURL(pageURL))
+	s.dirty = true
+}
+
+// page 返回已保存的页面
+func (s *State) page(pageURL string) (PageState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page, ok := s.Pages[parser.NormalizeURL(pageURL)]
+	return page, ok
+}
+
+// pageSaved 记录已保存的页面并移出队列，requestURL为重定向前的地址
+func (s *State) pageSaved(pageURL string, requestURL string, page PageState) {
+	key := parser.NormalizeURL(pageURL)
+	page.Aliases = nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if alias := parser.NormalizeURL(requestURL); requestURL != "" && alias != key {
+		page.Aliases = append(page.Aliases, alias)
+		delete(s.Queue, alias)
+	}
+	delete(s.Queue, key)
+	s.Pages[key] = page
+	s.dirty = true
+}
+
+// asset 返回已完成下载的资源
+func (s *State) asset(link string) (AssetState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	asset, ok := s.Assets[parser.NormalizeURL(link)]
+	return asset, ok
+}
+
+// queueAsset 记录即将下载的资源
+func (s *State) queueAsset(link string, kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.PendingAssets[parser.NormalizeURL(link)] = kind
+	s.dirty = true
+}
+
+// assetSaved 记录已完成下载的资源
+func (s *State) assetSaved(link string, asset AssetState) {
+	key := parser.NormalizeURL(link)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.PendingAssets, key)
+	s.Assets[key] = asset
+	s.dirty = true
+}
+
+// dropAsset 将不会保存的资源（如不支持的类型或超出大小限制）移出待下载列表
+func (s *State) dropAsset(link string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.PendingAssets, parser.NormalizeURL(link))
+	s.dirty = true
+}
+
+// pending 返回队列中的页面和待下载的资源，用于继续上次的爬取
+func (s *State) pending() (map[string]int, map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages := make(map[string]int, len(s.Queue))
+	for pageURL, depth := range s.Queue {
+		pages[pageURL] = depth
+	}
+	assets := make(map[string]string, len(s.PendingAssets))
+	for link, kind := range s.PendingAssets {
+		assets[link] = kind
+	}
+	return pages, assets
+}
+
+// savedPages 返回已保存的页面数量
+func (s *State) savedPages() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Pages)
+}
+
+// savedAssets 返回已保存资源的副本
+func (s *State) savedAssets() map[string]AssetState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assets := make(map[string]AssetState, len(s.Assets))
+	for link, asset := range s.Assets {
+		assets[link] = asset
+	}
+	return assets
+}
+
+// Changes 更新模式下本次克隆与上次相比新增、修改和删除的页面及资源URL
+type Changes struct {
+	// Added 新增的页面和资源
+	Added []string
+	// Modified 内容有变化并重新下载的页面和资源
+	Modified []string
+	// Removed 已不存在或不再被引用、已从项目中删除的页面和资源
+	Removed []string
+}
+
+// removedEntry 上次保存、本次已不存在的页面或资源，keepFile表示本地文件仍被其他URL使用
+type removedEntry struct {
+	url      string
+	path     string
+	page     bool
+	keepFile bool
+}
+
+// changes 与上次的爬取进度比较，withRemoved为false时（如爬取未完成）不统计删除，
+// 返回的removed用于删除本地文件；只有不再被引用或服务器返回404/410的资源算作删除，
+// 仍在待下载列表中（下载失败）的资源保留上次的文件
+func (s *State) changes(previous *State, withRemoved bool) (Changes, []removedEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous.mu.Lock()
+	defer previous.mu.Unlock()
+
+	var changes Changes
+	compare := func(link string, hash string, known bool, previousHash string) {
+		switch {
+		case !known:
+			changes.Added = append(changes.Added, link)
+		case hash != previousHash:
+			changes.Modified = append(changes.Modified, link)
+		}
+	}
+	for pageURL, page := range s.Pages {
+		previousPage, known := previous.Pages[pageURL]
+		compare(pageURL, page.SHA256, known, previousPage.SHA256)
+	}
+	for link, asset := range s.Assets {
+		previousAsset, known := previous.Assets[link]
+		compare(link, asset.SHA256, known, previousAsset.SHA256)
+	}
+
+	var removed []removedEntry
+	if withRemoved {
+		// 本地文件仍被其他页面或资源使用时只删除记录
+		inUse := make(map[string]bool)
+		for _, page := range s.Pages {
+			inUse[page.Path] = true
+		}
+		for _, asset := range s.Assets {
+			inUse[asset.Path] = true
+		}
+		for pageURL, page := range previous.Pages {
+			if _, ok := s.Pages[pageURL]; !ok {
+				changes.Removed = append(changes.Removed, pageURL)
+				removed = append(removed, removedEntry{url: pageURL, path: page.Path, page: true, keepFile: inUse[page.Path]})
+			}
+		}
+		for link, asset := range previous.Assets {
+			_, pending := s.PendingAssets[link]
+			if _, ok := s.Assets[link]; !ok && !pending {
+				changes.Removed = append(changes.Removed, link)
+				removed = append(removed, removedEntry{url: link, path: asset.Path, keepFile: inUse[asset.Path]})
+			}
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Removed)
+	return changes, removed
+}
@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestStateResume(t *testing.T) {
+	dir := t.TempDir()
+	state := NewState("https://Example.com")
+	state.queuePage("https://example.com/", 0)
+	state.queuePage("https://example.com/about", 1)
+	state.queuePage("https://example.com/docs#intro", 1)
+	state.pageSaved("https://example.com/", "https://example.com", PageState{Path: "index.html", ETag: `"v1"`})
+	state.queueAsset("https://example.com/a.css", "css")
+	state.queueAsset("https://example.com/b.js", "js")
+	state.assetSaved("https://example.com/a.css", AssetState{Path: "css/a.css", Size: 4, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"})
+	if err := state.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, assets := loaded.pending()
+	manifest := loaded.Manifest()
+	pagePath, _ := manifest.Page("https://example.com/")
+	assetPath, _ := manifest.Asset("https://example.com/a.css")
+
+	tables := []struct {
+		name string
+		ok   bool
+	}{
+		{"start url", loaded.StartURL == "https://example.com/"},
+		{"incomplete", !loaded.Complete},
+		{"saved page etag", loaded.Pages["https://example.com/"].ETag == `"v1"`},
+		{"queue", len(pages) == 2 && pages["https://example.com/docs"] == 1},
+		{"saved page not requeued", !loaded.queuePage("https://example.com/#top", 1)},
+		{"queued page not requeued", !loaded.queuePage("https://example.com/about", 2)},
+		{"new page queued", loaded.queuePage("https://example.com/new", 2)},
+		{"pending assets", len(assets) == 1 && assets["https://example.com/b.js"] == "js"},
+		{"saved asset", loaded.Assets["https://example.com/a.css"].Size == 4},
+		{"manifest", pagePath == "index.html" && assetPath == "css/a.css"},
+	}
+	for _, table := range tables {
+		if !table.ok {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s StateResume Failed: %s\n", red("[-]"), table.name)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s StateResume Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
+
+func TestStateChanges(t *testing.T) {
+	previous := NewState("https://example.com/")
+	previous.pageSaved("https://example.com/", "", PageState{Path: "index.html", SHA256: "a"})
+	previous.pageSaved("https://example.com/old", "", PageState{Path: "old/index.html", SHA256: "b"})
+	previous.assetSaved("https://example.com/a.css", AssetState{Path: "css/a.css", SHA256: "c"})
+	previous.assetSaved("https://example.com/b.js", AssetState{Path: "js/b.js", SHA256: "d"})
+	previous.assetSaved("https://example.com/c.png", AssetState{Path: "imgs/c.png", SHA256: "e"})
+
+	current := NewState("https://example.com/")
+	current.pageSaved("https://example.com/", "", PageState{Path: "index.html", SHA256: "a"})
+	current.pageSaved("https://example.com/new", "", PageState{Path: "new/index.html", SHA256: "f"})
+	current.assetSaved("https://example.com/a.css", AssetState{Path: "css/a.css", SHA256: "changed"})
+	current.assetSaved("https://example.com/b.js", AssetState{Path: "js/b.js", SHA256: "d"})
+	// 新资源使用了已删除资源的文件名
+	current.assetSaved("https://cdn.example.com/c.png", AssetState{Path: "imgs/c.png", SHA256: "g"})
+	// 更新时返回500的资源仍在待下载列表中，文件必须保留
+	previous.assetSaved("https://example.com/flaky.woff2", AssetState{Path: "fonts/flaky.woff2", SHA256: "h"})
+	current.queueAsset("https://example.com/flaky.woff2", "资源")
+
+	changes, removed := current.changes(previous, true)
+	partial, partialRemoved := current.changes(previous, false)
+	keep := make(map[string]bool)
+	for _, entry := range removed {
+		keep[entry.url] = entry.keepFile
+	}
+
+	tables := []struct {
+		name     string
+		result   []string
+		expected string
+	}{
+		{"added", changes.Added, "https://cdn.example.com/c.png https://example.com/new"},
+		{"modified", changes.Modified, "https://example.com/a.css"},
+		{"removed", changes.Removed, "https://example.com/c.png https://example.com/old"},
+		{"incomplete crawl", partial.Removed, ""},
+	}
+	for _, table := range tables {
+		result := strings.Join(table.result, " ")
+		if result != table.expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s StateChanges Failed: %s, expected %q got %q\n", red("[-]"), table.name, table.expected, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s StateChanges Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+	if len(removed) != 2 || !keep["https://example.com/c.png"] || keep["https://example.com/old"] || len(partialRemoved) != 0 {
+		t.Errorf("removed entries: %+v", removed)
+	}
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// rewriteWebManifest 将web app manifest中icons和screenshots的地址改写为本地路径，
+// 内容不是合法JSON时原样返回
+func rewriteWebManifest(data []byte, manifestURL string, fetchRef func(ref string) string) []byte {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return data
+	}
+
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return data
+	}
+
+	changed := false
+	for _, key := range []string{"icons", "screenshots"} {
+		images, ok := manifest[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, image := range images {
+			entry, ok := image.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			src, _ := entry["src"].(string)
+			u, err := base.Parse(src)
+			if src == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				continue
+			}
+			localPath := fetchRef(u.String())
+			if localPath == "" {
+				continue
+			}
+			entry["src"] = relativeAssetPath("misc", localPath)
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+
+	out, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 支持的项目打包格式
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// archiveTime 打包时所有条目使用的固定修改时间（zip能表示的最早时间），保证相同内容生成相同的归档
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ValidateArchiveFormat 检查打包格式是否受支持
+func ValidateArchiveFormat(format string) error {
+	switch format {
+	case ArchiveZip, ArchiveTarGz:
+		return nil
+	}
+	return fmt.Errorf("不支持的打包格式 %q，可选 %s 或 %s", format, ArchiveZip, ArchiveTarGz)
+}
+
+// WriteArchive 将项目目录打包写入w，条目按路径排序，时间、权限和属主都使用固定值，
+// 相同的项目内容总是生成完全相同的归档；归档根目录即项目目录，未完成写入的临时文件不会被打包
+func WriteArchive(w io.Writer, projectPath string, format string) error {
+	return WriteStorageArchive(w, NewDirStorage(projectPath), format)
+}
+
+// WriteStorageArchive 将后端中的项目打包写入w，规则与WriteArchive相同；
+// 本地目录保留空目录，其他后端中的目录按文件路径生成
+func WriteStorageArchive(w io.Writer, project Storage, format string) error {
+	if err := ValidateArchiveFormat(format); err != nil {
+		return err
+	}
+	var entries []archiveEntry
+	var err error
+	if local, ok := project.(*DirStorage); ok {
+		entries, err = archiveEntries(local.root)
+	} else {
+		entries, err = storageEntries(project)
+	}
+	if err != nil {
+		return err
+	}
+	if format == ArchiveZip {
+		return writeZip(w, project, entries)
+	}
+	return writeTarGz(w, project, entries)
+}
+
+// archiveEntry 归档中的一个目录或文件
+type archiveEntry struct {
+	// name 使用/分隔的相对路径，目录以/结尾
+	name string
+	dir  bool
+}
+
+// archiveEntries 按字典序返回项目中的目录和普通文件，跳过符号链接和临时文件
+func archiveEntries(projectPath string) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	// WalkDir按字典序遍历，结果顺序与文件系统无关
+	err := filepath.WalkDir(projectPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == projectPath {
+			return nil
+		}
+		rel, err := filepath.Rel(projectPath, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		switch {
+		case d.IsDir():
+			entries = append(entries, archiveEntry{name: name + "/", dir: true})
+		case d.Type().IsRegular() && !isTempFile(d.Name()):
+			entries = append(entries, archiveEntry{name: name})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// storageEntries 按字典序返回后端中的文件及其所在的目录
+func storageEntries(project Storage) ([]archiveEntry, error) {
+	names, err := project.List("")
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool)
+	var entries []archiveEntry
+	for _, name := range names {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if !dirs[dir] {
+				dirs[dir] = true
+				entries = append(entries, archiveEntry{name: dir + "/", dir: true})
+			}
+		}
+		entries = append(entries, archiveEntry{name: name})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+// writeZip 写入zip归档
+func writeZip(w io.Writer, project Storage, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Deflate,
+			Modified: archiveTime,
+		}
+		if entry.dir {
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | DirMode)
+		} else {
+			header.SetMode(FileMode)
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if !entry.dir {
+			if err := copyFile(fw, project, entry.name); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz 写入gzip压缩的tar归档，gzip头中不包含文件名和时间
+func writeTarGz(w io.Writer, project Storage, entries []archiveEntry) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:    entry.name,
+			ModTime: archiveTime,
+		}
+		if entry.dir {
+			header.Typeflag = tar.TypeDir
+			header.Mode = int64(DirMode)
+		} else {
+			info, err := project.Stat(entry.name)
+			if err != nil {
+				return err
+			}
+			header.Typeflag = tar.TypeReg
+			header.Mode = int64(FileMode)
+			header.Size = info.Size()
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !entry.dir {
+			if err := copyFile(tw, project, entry.name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// copyFile 将项目中的文件写入w
+func copyFile(w io.Writer, project Storage, name string) error {
+	f, err := project.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,91 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestWriteArchive(t *testing.T) {
+	// 内容相同但创建顺序和修改时间不同的两个项目
+	projects := []string{t.TempDir(), t.TempDir()}
+	files := []string{"index.html", "css/main.css", "imgs/a.png", "about/index.html"}
+	for i, projectPath := range projects {
+		for j := range files {
+			name := files[j]
+			if i == 1 {
+				name = files[len(files)-1-j]
+			}
+			os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0700)
+			os.WriteFile(filepath.Join(projectPath, name), []byte("content of "+name), 0600)
+			os.Chtimes(filepath.Join(projectPath, name), time.Now(), time.Now().Add(time.Duration(i)*time.Hour))
+		}
+		os.Mkdir(filepath.Join(projectPath, "js"), 0700)
+		os.WriteFile(filepath.Join(projectPath, ".index.html.123.tmp"), []byte("partial"), 0600)
+	}
+
+	expected := "about/,about/index.html,css/,css/main.css,imgs/,imgs/a.png,index.html,js/"
+	for _, format := range []string{ArchiveZip, ArchiveTarGz} {
+		var archives [2]bytes.Buffer
+		for i, projectPath := range projects {
+			if err := WriteArchive(&archives[i], projectPath, format); err != nil {
+				t.Fatal(err)
+			}
+		}
+		names, err := archiveNames(archives[0].Bytes(), format)
+		result := strings.Join(names, ",")
+		if err != nil || result != expected || !bytes.Equal(archives[0].Bytes(), archives[1].Bytes()) {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s WriteArchive Failed: %s, expected %s got %s, identical %v (%v)\n", red("[-]"), format, expected, result, bytes.Equal(archives[0].Bytes(), archives[1].Bytes()), err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s WriteArchive Passing: %s \n", green("[+]"), format)
+		}
+	}
+
+	if err := WriteArchive(io.Discard, projects[0], "rar"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+// archiveNames 按顺序返回归档中的条目名称
+func archiveNames(data []byte, format string) ([]string, error) {
+	var names []string
+	if format == ArchiveZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		return names, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, header.Name)
+	}
+}
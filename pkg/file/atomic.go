@@ -0,0 +1,126 @@
+package file
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FileMode 项目中保存的文件权限
+	FileMode os.FileMode = 0644
+	// DirMode 项目中创建的目录权限
+	DirMode os.FileMode = 0755
+)
+
+// AtomicFile 先写入同目录下的临时文件，Commit时fsync并原子重命名为目标文件，
+// 写入中断或取消时目标文件保持不变，不会出现写了一半的文件
+type AtomicFile struct {
+	f    *os.File
+	path string
+	done bool
+}
+
+// CreateAtomic 在目标文件所在目录创建临时文件，目录不存在时自动创建
+func CreateAtomic(path string) (*AtomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, DirMode); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{f: f, path: path}, nil
+}
+
+// Write 实现io.Writer接口
+func (a *AtomicFile) Write(p []byte) (int, error) {
+	return a.f.Write(p)
+}
+
+// Commit 将临时文件写入磁盘并替换目标文件，失败时删除临时文件
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+
+	err := a.f.Sync()
+	if closeErr := a.f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(a.f.Name(), FileMode)
+	}
+	if err == nil {
+		err = os.Rename(a.f.Name(), a.path)
+	}
+	if err != nil {
+		os.Remove(a.f.Name())
+		return err
+	}
+	syncDir(filepath.Dir(a.path))
+	return nil
+}
+
+// Abort 放弃写入并删除临时文件，Commit之后调用不会有任何效果
+func (a *AtomicFile) Abort() {
+	if a.done {
+		return
+	}
+	a.done = true
+	a.f.Close()
+	os.Remove(a.f.Name())
+}
+
+// WriteFileAtomic 将r的内容流式写入path，成功时原子替换目标文件
+func WriteFileAtomic(path string, r io.Reader) (int64, error) {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Abort()
+		return n, err
+	}
+	return n, f.Commit()
+}
+
+// RemoveTempFiles 删除目录中写入中断（如进程被终止）时遗留的临时文件，返回删除的数量；
+// 同一目录中不能有正在进行的写入
+func RemoveTempFiles(dir string) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !isTempFile(d.Name()) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// isTempFile 判断是否是CreateAtomic创建的临时文件
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp")
+}
+
+// syncDir 尽量将目录项写入磁盘，使重命名在断电后仍然有效，不支持的平台忽略错误
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,89 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// failingReader 读取部分内容后返回错误，模拟下载中断
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tables := []struct {
+		name     string
+		existing string
+		body     io.Reader
+		expected string
+	}{
+		{"new.css", "", strings.NewReader("body{}"), "body{}"},
+		{"shorter.js", "var long = 1234567890;", strings.NewReader("var a;"), "var a;"},
+		{"interrupted.png", "old", &failingReader{data: "partial"}, "old"},
+		{"sub/dir/page.html", "", strings.NewReader("<html></html>"), "<html></html>"},
+	}
+	for _, table := range tables {
+		dir := t.TempDir()
+		target := filepath.Join(dir, filepath.FromSlash(table.name))
+		if table.existing != "" {
+			os.WriteFile(target, []byte(table.existing), FileMode)
+		}
+		_, err := WriteFileAtomic(target, table.body)
+
+		data, _ := os.ReadFile(target)
+		info, statErr := os.Stat(target)
+		// 除目标文件外不应留下临时文件
+		entries, _ := os.ReadDir(filepath.Dir(target))
+		if string(data) != table.expected || statErr != nil || info.Mode().Perm() != FileMode || len(entries) != 1 {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s WriteFileAtomic Failed: %s, expected %q got %q (%v, %d entries)\n", red("[-]"), table.name, table.expected, data, err, len(entries))
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s WriteFileAtomic Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
+
+func TestRemoveTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), DirMode)
+	// 进程在写入时被终止，临时文件没有提交也没有删除
+	f, err := CreateAtomic(filepath.Join(dir, "css", "site.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), FileMode)
+	os.WriteFile(filepath.Join(dir, ".well-known"), []byte("kept"), FileMode)
+
+	if size, _ := GetFolderSize(dir); size != int64(len("<html></html>")+len("kept")) {
+		t.Errorf("temp file counted in folder size: %d", size)
+	}
+	removed, err := RemoveTempFiles(dir)
+	entries, _ := os.ReadDir(filepath.Join(dir, "css"))
+	if err != nil || removed != 1 || len(entries) != 0 {
+		t.Errorf("expected temp file removed, got %d removed, %d left (%v)", removed, len(entries), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".well-known")); err != nil {
+		t.Errorf("non-temp file removed: %v", err)
+	}
+	f.Abort()
+}
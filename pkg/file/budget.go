@@ -0,0 +1,146 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrBudgetExceeded 写入会超出文件夹大小限制
+	ErrBudgetExceeded = errors.New("超出文件夹大小限制")
+	// ErrQuotaExceeded 写入会超出该类资源的大小配额
+	ErrQuotaExceeded = errors.New("超出该类资源的大小配额")
+)
+
+// Budget 项目文件夹的字节预算，所有写入共用同一个计数器，不需要反复遍历文件夹。
+// nil表示不限制
+type Budget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	// parent 子预算的预留同时计入父预算
+	parent *Budget
+	// exceeded 空间不足时返回的错误
+	exceeded error
+}
+
+// NewBudget 创建大小预算，used为项目中已有文件的大小，limit<=0时返回nil（不限制）
+func NewBudget(limit int64, used int64) *Budget {
+	if limit <= 0 {
+		return nil
+	}
+	return &Budget{limit: limit, used: used, exceeded: ErrBudgetExceeded}
+}
+
+// Sub 创建子预算（如某类资源的配额），预留同时计入b，
+// limit<=0时直接返回b，b为nil时子预算单独生效
+func (b *Budget) Sub(limit int64) *Budget {
+	if limit <= 0 {
+		return b
+	}
+	return &Budget{limit: limit, parent: b, exceeded: ErrQuotaExceeded}
+}
+
+// Reserve 预留n字节，本预算或父预算空间不足时返回ErrQuotaExceeded或ErrBudgetExceeded，
+// 且不预留任何空间
+func (b *Budget) Reserve(n int64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used+n > b.limit {
+		return b.exceeded
+	}
+	if err := b.parent.Reserve(n); err != nil {
+		return err
+	}
+	b.used += n
+	return nil
+}
+
+// Release 归还之前预留的n字节
+func (b *Budget) Release(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.parent.Release(n)
+}
+
+// Add 计入已经存在的n字节（如替换失败后保留的旧文件），不检查上限
+func (b *Budget) Add(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used += n
+	b.mu.Unlock()
+	b.parent.Add(n)
+}
+
+// Used 返回已使用和已预留的字节数
+func (b *Budget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Limit 返回字节上限，0表示不限制
+func (b *Budget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// BudgetWriter 写入时计入预算的Writer，超出预留的部分按需追加预留，
+// 预算不足时返回ErrBudgetExceeded，调用方应丢弃已写入的内容并调用Abort
+type BudgetWriter struct {
+	w        io.Writer
+	budget   *Budget
+	reserved int64
+	written  int64
+}
+
+// NewBudgetWriter 包装w，reserved为已经通过Reserve预留的字节数（如Content-Length）
+func NewBudgetWriter(w io.Writer, budget *Budget, reserved int64) *BudgetWriter {
+	return &BudgetWriter{w: w, budget: budget, reserved: reserved}
+}
+
+// Write 实现io.Writer接口
+func (w *BudgetWriter) Write(p []byte) (int, error) {
+	if need := w.written + int64(len(p)) - w.reserved; need > 0 {
+		if err := w.budget.Reserve(need); err != nil {
+			return 0, err
+		}
+		w.reserved += need
+	}
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Written 返回已写入的字节数
+func (w *BudgetWriter) Written() int64 {
+	return w.written
+}
+
+// Commit 写入完成，归还多预留的空间
+func (w *BudgetWriter) Commit() {
+	w.budget.Release(w.reserved - w.written)
+	w.reserved = w.written
+}
+
+// Abort 放弃写入的内容，归还全部预留空间
+func (w *BudgetWriter) Abort() {
+	w.budget.Release(w.reserved)
+	w.reserved = 0
+	w.written = 0
+}
@@ -0,0 +1,87 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestBudgetWriter(t *testing.T) {
+	tables := []struct {
+		limit    int64
+		used     int64
+		reserved int64
+		data     string
+		err      error
+		expected int64
+	}{
+		{0, 0, 0, "unlimited", nil, 0},
+		{10, 0, 0, "12345", nil, 5},
+		{10, 5, 0, "123456", ErrBudgetExceeded, 5},
+		{10, 0, 8, "1234", nil, 4},
+		{10, 2, 8, "123456789", ErrBudgetExceeded, 2},
+		{10, 0, 3, "1234567890", nil, 10},
+	}
+	for _, table := range tables {
+		budget := NewBudget(table.limit, table.used)
+		var err error
+		if err = budget.Reserve(table.reserved); err == nil {
+			var buf bytes.Buffer
+			w := NewBudgetWriter(&buf, budget, table.reserved)
+			_, err = w.Write([]byte(table.data))
+			if err != nil {
+				w.Abort()
+			} else {
+				w.Commit()
+			}
+		}
+		if err != table.err || budget.Used() != table.expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s BudgetWriter Failed: %d/%d %q, expected %v %d got %v %d\n", red("[-]"), table.used, table.limit, table.data, table.err, table.expected, err, budget.Used())
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s BudgetWriter Passing: %d/%d %q \n", green("[+]"), table.used, table.limit, strings.TrimSpace(table.data))
+		}
+	}
+}
+
+func TestBudgetSub(t *testing.T) {
+	tables := []struct {
+		limit    int64
+		quota    int64
+		reserves []int64
+		err      error
+		used     int64
+		subUsed  int64
+	}{
+		{100, 10, []int64{5, 5}, nil, 10, 10},
+		{100, 10, []int64{5, 6}, ErrQuotaExceeded, 5, 5},
+		{10, 50, []int64{8, 5}, ErrBudgetExceeded, 8, 8},
+		{0, 10, []int64{10, 1}, ErrQuotaExceeded, 0, 10},
+		{10, 0, []int64{10, 1}, ErrBudgetExceeded, 10, 10},
+	}
+	for _, table := range tables {
+		budget := NewBudget(table.limit, 0)
+		sub := budget.Sub(table.quota)
+		var err error
+		for _, n := range table.reserves {
+			if err = sub.Reserve(n); err != nil {
+				break
+			}
+		}
+		if err != table.err || budget.Used() != table.used || sub.Used() != table.subUsed {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s BudgetSub Failed: %d/%d %v, expected %v %d/%d got %v %d/%d\n", red("[-]"), table.quota, table.limit, table.reserves, table.err, table.subUsed, table.used, err, sub.Used(), budget.Used())
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s BudgetSub Passing: %d/%d %v \n", green("[+]"), table.quota, table.limit, table.reserves)
+		}
+	}
+}
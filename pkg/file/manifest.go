@@ -0,0 +1,229 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+// ManifestName 项目中资源清单的文件名
+const ManifestName = "manifest.json"
+
+// Manifest 记录项目中每个源URL对应的唯一本地路径，下载和链接重构共用同一份记录
+type Manifest struct {
+	// Pages 页面URL到项目内相对路径的映射
+	Pages map[string]string `json:"pages"`
+	// Assets 资源URL到项目内相对路径的映射
+	Assets map[string]string `json:"assets"`
+	// Blobs 使用共享存储时资源本地路径到共享存储中内容的SHA-256
+	Blobs map[string]string `json:"blobs,omitempty"`
+
+	mu sync.Mutex
+	// owners 本地路径到占用它的URL，用于检测冲突
+	owners map[string]string
+}
+
+// NewManifest 创建空的资源清单
+func NewManifest() *Manifest {
+	return &Manifest{
+		Pages:  make(map[string]string),
+		Assets: make(map[string]string),
+		Blobs:  make(map[string]string),
+		owners: make(map[string]string),
+	}
+}
+
+// LoadManifest 读取项目中的manifest.json
+func LoadManifest(projectPath string) (*Manifest, error) {
+	return LoadManifestFrom(NewDirStorage(projectPath))
+}
+
+// LoadManifestFrom 读取后端中项目的manifest.json
+func LoadManifestFrom(project Storage) (*Manifest, error) {
+	data, err := ReadStorageFile(project, ManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewManifest()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("解析%s失败: %w", ManifestName, err)
+	}
+	if m.Pages == nil {
+		m.Pages = make(map[string]string)
+	}
+	if m.Assets == nil {
+		m.Assets = make(map[string]string)
+	}
+	if m.Blobs == nil {
+		m.Blobs = make(map[string]string)
+	}
+	for link, localPath := range m.Pages {
+		m.owners[localPath] = link
+	}
+	for link, localPath := range m.Assets {
+		m.owners[localPath] = link
+	}
+	return m, nil
+}
+
+// Save 将清单保存为项目中的manifest.json
+func (m *Manifest) Save(projectPath string) error {
+	return m.SaveTo(NewDirStorage(projectPath))
+}
+
+// SaveTo 将清单保存为后端中项目的manifest.json
+func (m *Manifest) SaveTo(project Storage) error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = WriteStorageFile(project, ManifestName, bytes.NewReader(data))
+	return err
+}
+
+// AddPage 记录页面并返回其唯一且安全的本地路径，aliases为指向同一页面的其他URL（如重定向前的地址）
+func (m *Manifest) AddPage(pageURL string, pagePath string, aliases ...string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pageURL = parser.NormalizeURL(pageURL)
+	if existing, ok := m.Pages[pageURL]; ok {
+		return existing
+	}
+	pagePath = m.claim(SanitizePath(pagePath), pageURL)
+	m.Pages[pageURL] = pagePath
+	for _, alias := range aliases {
+		alias = parser.NormalizeURL(alias)
+		if _, exists := m.Pages[alias]; !exists {
+			m.Pages[alias] = pagePath
+		}
+	}
+	return pagePath
+}
+
+// AddAsset 记录资源并返回其唯一且安全的本地路径，同名文件会添加URL哈希后缀
+func (m *Manifest) AddAsset(link string, localPath string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link = parser.NormalizeURL(link)
+	if existing, ok := m.Assets[link]; ok {
+		return existing
+	}
+	localPath = m.claim(SanitizePath(localPath), link)
+	m.Assets[link] = localPath
+	return localPath
+}
+
+// RemoveAsset 删除资源记录并释放其本地路径，用于下载失败的资源
+func (m *Manifest) RemoveAsset(link string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link = parser.NormalizeURL(link)
+	if localPath, ok := m.Assets[link]; ok {
+		delete(m.owners, localPath)
+		delete(m.Blobs, localPath)
+		delete(m.Assets, link)
+	}
+}
+
+// SetBlob 记录资源文件在共享存储中对应的内容哈希
+func (m *Manifest) SetBlob(localPath string, sum string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Blobs[localPath] = sum
+}
+
+// RemovePage 删除页面及指向同一文件的其他URL的记录，并释放其本地路径
+func (m *Manifest) RemovePage(pageURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pagePath, ok := m.Pages[parser.NormalizeURL(pageURL)]
+	if !ok {
+		return
+	}
+	for link, localPath := range m.Pages {
+		if localPath == pagePath {
+			delete(m.Pages, link)
+		}
+	}
+	delete(m.owners, pagePath)
+}
+
+// Page 返回页面的本地路径
+func (m *Manifest) Page(pageURL string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	localPath, ok := m.Pages[parser.NormalizeURL(pageURL)]
+	return localPath, ok
+}
+
+// Asset 返回资源的本地路径
+func (m *Manifest) Asset(link string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	localPath, ok := m.Assets[parser.NormalizeURL(link)]
+	return localPath, ok
+}
+
+// PagePaths 返回每个页面文件及其对应的URL，同一文件有多个URL时取字典序最小的
+func (m *Manifest) PagePaths() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pageURLs := make(map[string]string)
+	for pageURL, pagePath := range m.Pages {
+		if existing, ok := pageURLs[pagePath]; !ok || pageURL < existing {
+			pageURLs[pagePath] = pageURL
+		}
+	}
+	return pageURLs
+}
+
+// AssetURLs 返回按字典序排列的资源URL
+func (m *Manifest) AssetURLs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	links := make([]string, 0, len(m.Assets))
+	for link := range m.Assets {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	return links
+}
+
+// claim 占用本地路径，已被其他URL占用时改用带哈希后缀的文件名
+func (m *Manifest) claim(localPath string, link string) string {
+	if owner, taken := m.owners[localPath]; !taken || owner == link {
+		m.owners[localPath] = link
+		return localPath
+	}
+
+	sum := sha1.Sum([]byte(link))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	candidate := AddNameSuffix(localPath, suffix)
+	for i := 2; ; i++ {
+		if _, taken := m.owners[candidate]; !taken {
+			break
+		}
+		candidate = AddNameSuffix(localPath, suffix+"_"+strconv.Itoa(i))
+	}
+	m.owners[candidate] = link
+	return candidate
+}
@@ -0,0 +1,38 @@
+package file
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestManifestAddAsset(t *testing.T) {
+	m := NewManifest()
+	tables := []struct {
+		url      string
+		path     string
+		expected string
+	}{
+		{"https://tesla.com/a/main.js", "js/main.js", "js/main.js"},
+		{"https://tesla.com/b/main.js", "js/main.js", "js/main_02078351.js"},
+		{"https://tesla.com/a/main.js#x", "js/main.js", "js/main.js"},
+		{"https://tesla.com/image?id=5", "imgs/image.png", "imgs/image.png"},
+		{"https://tesla.com/image?id=6", "imgs/image.png", "imgs/image_8ff6170b.png"},
+		{"https://tesla.com/x/..%2F..%2Fevil.js", "js/../../evil.js", "js/evil.js"},
+		{"https://tesla.com/con.css", "css/con.css", "css/_con.css"},
+	}
+	for _, table := range tables {
+		result := m.AddAsset(table.url, table.path)
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s ManifestAddAsset Failed: %s , expected %s got %s \n", red("[-]"), table.url, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s ManifestAddAsset Passing: %s \n", green("[+]"), table.url)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// RecordingsName 项目中接口响应记录的文件名
+	RecordingsName = "recordings.json"
+	// RecordingsDir 项目中保存接口响应体的目录
+	RecordingsDir = "api"
+)
+
+// Recording 一个记录的接口响应
+type Recording struct {
+	// Method 请求方法
+	Method string `json:"method"`
+	// URL 请求的绝对URL
+	URL string `json:"url"`
+	// Status 响应状态码
+	Status int `json:"status"`
+	// ContentType 响应的Content-Type
+	ContentType string `json:"contentType,omitempty"`
+	// Path 响应体在项目中的相对路径
+	Path string `json:"path"`
+}
+
+// Recordings 页面脚本请求的接口（fetch/XMLHttpRequest）响应，本地预览时按相同的路径返回，
+// 使依赖接口数据的单页应用可以离线打开
+type Recordings struct {
+	// Entries 以"方法 URL"为键的记录
+	Entries map[string]Recording `json:"entries"`
+
+	mu sync.Mutex
+}
+
+// NewRecordings 创建空的接口响应记录
+func NewRecordings() *Recordings {
+	return &Recordings{Entries: make(map[string]Recording)}
+}
+
+// LoadRecordings 读取项目中的recordings.json
+func LoadRecordings(projectPath string) (*Recordings, error) {
+	return LoadRecordingsFrom(NewDirStorage(projectPath))
+}
+
+// LoadRecordingsFrom 读取后端中项目的recordings.json
+func LoadRecordingsFrom(project Storage) (*Recordings, error) {
+	data, err := ReadStorageFile(project, RecordingsName)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecordings()
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("解析%s失败: %w", RecordingsName, err)
+	}
+	if r.Entries == nil {
+		r.Entries = make(map[string]Recording)
+	}
+	return r, nil
+}
+
+// Save 将记录保存为项目中的recordings.json
+func (r *Recordings) Save(projectPath string) error {
+	return r.SaveTo(NewDirStorage(projectPath))
+}
+
+// SaveTo 将记录保存为后端中项目的recordings.json
+func (r *Recordings) SaveTo(project Storage) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = WriteStorageFile(project, RecordingsName, bytes.NewReader(data))
+	return err
+}
+
+// Add 将响应体写入项目的api目录并记录，同一请求再次记录时覆盖之前的响应；method为空表示GET
+func (r *Recordings) Add(projectPath string, method string, link string, status int, contentType string, body []byte) (Recording, error) {
+	return r.AddTo(NewDirStorage(projectPath), method, link, status, contentType, body)
+}
+
+// AddTo 与Add相同，响应体写入后端中项目的api目录
+func (r *Recordings) AddTo(project Storage, method string, link string, status int, contentType string, body []byte) (Recording, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	key := strings.ToUpper(method) + " " + link
+	sum := sha1.Sum([]byte(key))
+	recording := Recording{
+		Method:      strings.ToUpper(method),
+		URL:         link,
+		Status:      status,
+		ContentType: contentType,
+		Path:        RecordingsDir + "/" + hex.EncodeToString(sum[:]),
+	}
+	if _, err := WriteStorageFile(project, recording.Path, bytes.NewReader(body)); err != nil {
+		return Recording{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Entries[key] = recording
+	return recording, nil
+}
+
+// Len 返回记录的数量
+func (r *Recordings) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Entries)
+}
+
+// Lookup 返回本地预览时请求对应的记录，requestURI为路径和查询参数（如/api/items?page=2），不区分主机：
+// 先按路径和查询参数精确匹配，没有时匹配同一路径的其他查询参数（如防缓存的时间戳），HEAD请求使用GET的记录
+func (r *Recordings) Lookup(method string, requestURI string) (Recording, bool) {
+	method = strings.ToUpper(method)
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	requestPath, _, _ := strings.Cut(requestURI, "?")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]string, 0, len(r.Entries))
+	for key := range r.Entries {
+		keys = append(keys, key)
+	}
+	// 多个主机有相同路径时结果固定
+	sort.Strings(keys)
+
+	var fallback *Recording
+	for _, key := range keys {
+		recording := r.Entries[key]
+		u, err := url.Parse(recording.URL)
+		if err != nil || recording.Method != method {
+			continue
+		}
+		if u.RequestURI() == requestURI {
+			return recording, true
+		}
+		if fallback == nil && u.EscapedPath() == requestPath {
+			fallback = &recording
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Recording{}, false
+}
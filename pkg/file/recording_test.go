@@ -0,0 +1,65 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRecordings(t *testing.T) {
+	projectPath := t.TempDir()
+	recordings := NewRecordings()
+	for _, entry := range []struct {
+		link string
+		body string
+	}{
+		{"https://example.com/api/items", `["a"]`},
+		{"https://example.com/api/items?page=2", `["b"]`},
+		{"https://cdn.example.com/data/config.json", `{}`},
+	} {
+		if _, err := recordings.Add(projectPath, "", entry.link, 200, "application/json", []byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recordings.Save(projectPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRecordings(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		method     string
+		requestURI string
+		expected   string
+	}{
+		{"GET", "/api/items", `["a"]`},
+		{"GET", "/api/items?page=2", `["b"]`},
+		{"HEAD", "/api/items?page=2", `["b"]`},
+		// 查询参数不同时返回同一路径的记录
+		{"GET", "/api/items?_=1700000000", `["a"]`},
+		{"GET", "/data/config.json", `{}`},
+		{"POST", "/api/items", ""},
+		{"GET", "/api/other", ""},
+	}
+	for _, table := range tables {
+		result := ""
+		if recording, ok := loaded.Lookup(table.method, table.requestURI); ok {
+			body, _ := os.ReadFile(filepath.Join(projectPath, filepath.FromSlash(recording.Path)))
+			result = string(body)
+		}
+		if result != table.expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Recordings Failed: %s %s, expected %s got %s\n", red("[-]"), table.method, table.requestURI, table.expected, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Recordings Passing: %s %s \n", green("[+]"), table.method, table.requestURI)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxNameLength 单个文件名或目录名的最大字节数，超出的名称会被截断
+const MaxNameLength = 255
+
+// ErrUnsafePath 路径不安全，写入会逃出项目目录或在部分系统上无法创建
+var ErrUnsafePath = errors.New("不安全的文件路径")
+
+// invalidNameChars Windows文件名中不允许的字符，路径分隔符也在其中
+const invalidNameChars = `<>:"/\|?*`
+
+// reservedNames Windows保留的设备名，带扩展名时同样不可用（如con.txt）
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"CONIN$": true, "CONOUT$": true,
+}
+
+// lookalikeRunes 外观类似路径分隔符或点号的字符，替换以免文件名看起来像路径
+var lookalikeRunes = map[rune]bool{
+	'\u2215': true, // division slash
+	'\u2044': true, // fraction slash
+	'\uFF0F': true, // fullwidth solidus
+	'\u29F8': true, // big solidus
+	'\uFF3C': true, // fullwidth reverse solidus
+	'\u29F9': true, // big reverse solidus
+	'\uFE68': true, // small reverse solidus
+	'\u2024': true, // one dot leader
+	'\u2025': true, // two dot leader
+	'\u2026': true, // horizontal ellipsis
+	'\uFF0E': true, // fullwidth full stop
+	'\uFE52': true, // small full stop
+}
+
+// SanitizeName 将任意字符串转换为可以安全使用的单个文件名：
+// 统一为NFC形式，删除控制字符和不可见的格式字符（如双向文本覆盖），
+// 替换非法字符和形似分隔符的字符，避开保留设备名并限制长度
+func SanitizeName(name string) string {
+	name = norm.NFC.String(strings.ToValidUTF8(name, "_"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.Is(unicode.Cc, r) || unicode.Is(unicode.Cf, r):
+			// 不可见字符直接删除
+		case strings.ContainsRune(invalidNameChars, r) || lookalikeRunes[r]:
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	// 删除字符后组合字符可能重新相邻，再次规范化
+	name = norm.NFC.String(b.String())
+
+	// Windows会去掉结尾的点和空格，开头的空格容易被忽略
+	name = strings.TrimLeft(name, " ")
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "unnamed_file"
+	}
+	if isReservedName(name) {
+		name = "_" + name
+	}
+	return truncateName(name, MaxNameLength)
+}
+
+// SanitizePath 对"/"分隔的相对路径逐段调用SanitizeName，去掉空段、"."和".."，
+// 结果总是可以通过ValidatePath
+func SanitizePath(rel string) string {
+	rel = strings.ReplaceAll(rel, `\`, "/")
+	var parts []string
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." || part == ".." {
+			continue
+		}
+		parts = append(parts, SanitizeName(part))
+	}
+	if len(parts) == 0 {
+		return "unnamed_file"
+	}
+	return strings.Join(parts, "/")
+}
+
+// ValidateName 检查单个文件名是否安全，不安全时返回包含原因的ErrUnsafePath
+func ValidateName(name string) error {
+	reason := ""
+	switch {
+	case name == "" || name == "." || name == "..":
+		reason = "空名称或相对目录"
+	case len(name) > MaxNameLength:
+		reason = fmt.Sprintf("名称超过 %d 字节", MaxNameLength)
+	case !utf8.ValidString(name):
+		reason = "无效的UTF-8编码"
+	case !norm.NFC.IsNormalString(name):
+		reason = "不是NFC规范形式"
+	case strings.ContainsAny(name, invalidNameChars):
+		reason = "包含非法字符或路径分隔符"
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") || strings.HasPrefix(name, " "):
+		reason = "以点或空格结尾，或以空格开头"
+	case isReservedName(name):
+		reason = "系统保留的设备名"
+	default:
+		for _, r := range name {
+			if unicode.Is(unicode.Cc, r) || unicode.Is(unicode.Cf, r) || lookalikeRunes[r] {
+				reason = fmt.Sprintf("包含不可见或易混淆的字符 %U", r)
+				break
+			}
+		}
+	}
+	if reason != "" {
+		return fmt.Errorf("%w: %q %s", ErrUnsafePath, name, reason)
+	}
+	return nil
+}
+
+// ValidatePath 检查"/"分隔的项目内相对路径，拒绝绝对路径、卷名和".."等不安全的路径段
+func ValidatePath(rel string) error {
+	if rel == "" {
+		return fmt.Errorf("%w: 空路径", ErrUnsafePath)
+	}
+	if strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, `\`) || filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return fmt.Errorf("%w: %q 是绝对路径", ErrUnsafePath, rel)
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if err := ValidateName(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SafePath 校验项目内相对路径rel（"/"分隔）并返回其在projectPath下的完整路径，
+// 保证结果不会通过".."、绝对路径或已存在的符号链接逃出项目目录
+func SafePath(projectPath string, rel string) (string, error) {
+	if err := ValidatePath(rel); err != nil {
+		return "", err
+	}
+	root, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(root, filepath.FromSlash(rel))
+	if !within(root, full) {
+		return "", fmt.Errorf("%w: %q 不在项目目录内", ErrUnsafePath, rel)
+	}
+
+	// 已存在的上级目录可能是指向项目外的符号链接
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		// 项目目录尚未创建时不可能存在符号链接
+		return full, nil
+	}
+	for dir := filepath.Dir(full); within(root, dir); dir = filepath.Dir(dir) {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		if !within(realRoot, realDir) {
+			return "", fmt.Errorf("%w: %q 经符号链接指向项目目录之外", ErrUnsafePath, rel)
+		}
+		break
+	}
+	return full, nil
+}
+
+// within 判断path是否为root或其子路径
+func within(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// isReservedName 判断名称去掉扩展名后是否为保留设备名
+func isReservedName(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+// AddNameSuffix 在相对路径的文件名和扩展名之间插入后缀（如"_1a2b3c4d"），
+// 文件名过长时先截断主干，保证结果不超过MaxNameLength
+func AddNameSuffix(rel string, suffix string) string {
+	dir, name := path.Split(rel)
+	ext := path.Ext(name)
+	if len(ext)+len(suffix) > MaxNameLength/2 {
+		ext = ""
+	}
+	stem := truncateBytes(strings.TrimSuffix(name, ext), MaxNameLength-len(ext)-len(suffix))
+	return dir + stem + suffix + ext
+}
+
+// truncateName 将名称截断到max字节以内，尽量保留扩展名
+func truncateName(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+	ext := filepath.Ext(name)
+	if len(ext) > max/2 {
+		ext = ""
+	}
+	stem := strings.TrimRight(truncateBytes(strings.TrimSuffix(name, ext), max-len(ext)), ". ")
+	if stem == "" {
+		stem = "unnamed_file"
+	}
+	return stem + ext
+}
+
+// truncateBytes 将字符串截断到max字节以内，不截断多字节字符
+func truncateBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
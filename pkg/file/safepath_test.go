@@ -0,0 +1,189 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tables := []struct {
+		name     string
+		expected string
+	}{
+		{"main.css", "main.css"},
+		{"", "unnamed_file"},
+		{"..", "unnamed_file"},
+		{"a:b*c?.js", "a_b_c_.js"},
+		{`back\slash.png`, "back_slash.png"},
+		{"CON", "_CON"},
+		{"con.txt", "_con.txt"},
+		{"Lpt1.css", "_Lpt1.css"},
+		{"console.js", "console.js"},
+		{"trailing. . ", "trailing"},
+		{"  leading.css", "leading.css"},
+		{"evil\u202Egnp.js", "evilgnp.js"},
+		{"zero\u200Bwidth.css", "zerowidth.css"},
+		{"tab\tnew\nline.js", "tabnewline.js"},
+		{"\uFF0E\uFF0E\uFF0Fetc", "___etc"},
+		{"a\u2215b.png", "a_b.png"},
+		{"cafe\u0301.css", "caf\u00e9.css"},
+		{"bad\xffutf8.js", "bad_utf8.js"},
+		{"\u4e2d\u6587.png", "\u4e2d\u6587.png"},
+		{strings.Repeat("a", 300) + ".js", strings.Repeat("a", 252) + ".js"},
+		{strings.Repeat("\u4e2d", 100) + ".png", strings.Repeat("\u4e2d", 83) + ".png"},
+	}
+	for _, table := range tables {
+		result := SanitizeName(table.name)
+		if result != table.expected || ValidateName(result) != nil {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s SanitizeName Failed: %q, expected %q got %q (%v)\n", red("[-]"), table.name, table.expected, result, ValidateName(result))
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s SanitizeName Passing: %q \n", green("[+]"), table.name)
+		}
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	tables := []struct {
+		rel      string
+		expected string
+	}{
+		{"css/main.css", "css/main.css"},
+		{"docs/intro.html", "docs/intro.html"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"/abs/path.html", "abs/path.html"},
+		{`..\..\windows\win.ini`, "windows/win.ini"},
+		{"a//./b/index.html", "a/b/index.html"},
+		{"nul/aux.html", "_nul/_aux.html"},
+		{"C:/x.css", "C_/x.css"},
+		{"", "unnamed_file"},
+	}
+	for _, table := range tables {
+		result := SanitizePath(table.rel)
+		if result != table.expected || ValidatePath(result) != nil {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s SanitizePath Failed: %q, expected %q got %q (%v)\n", red("[-]"), table.rel, table.expected, result, ValidatePath(result))
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s SanitizePath Passing: %q \n", green("[+]"), table.rel)
+		}
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tables := []struct {
+		rel  string
+		safe bool
+	}{
+		{"css/main.css", true},
+		{"docs/deep/page/index.html", true},
+		{"imgs/\u4e2d\u6587.png", true},
+		{"", false},
+		{"../secret", false},
+		{"css/../../secret", false},
+		{"./index.html", false},
+		{"css//main.css", false},
+		{"/etc/passwd", false},
+		{`\\server\share\x`, false},
+		{"C:/windows/x.css", false},
+		{`css\..\..\x.css`, false},
+		{"imgs/CON.png", false},
+		{"imgs/com9", false},
+		{"js/app.js.", false},
+		{"js/app.js ", false},
+		{"js/evil\u202Egnp.js", false},
+		{"js/\uFF0E\uFF0E", false},
+		{"css/cafe\u0301.css", false},
+		{"js/bad\xff.js", false},
+		{"js/new\nline.js", false},
+		{"js/" + strings.Repeat("a", 256), false},
+	}
+	for _, table := range tables {
+		err := ValidatePath(table.rel)
+		if (err == nil) != table.safe || (err != nil && !errors.Is(err, ErrUnsafePath)) {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s ValidatePath Failed: %q, expected safe=%v got %v\n", red("[-]"), table.rel, table.safe, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s ValidatePath Passing: %q \n", green("[+]"), table.rel)
+		}
+	}
+}
+
+func TestSafePath(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	outside := filepath.Join(root, "outside")
+	os.MkdirAll(filepath.Join(project, "css"), DirMode)
+	os.MkdirAll(outside, DirMode)
+	// 项目内指向项目外的符号链接
+	symlinks := os.Symlink(outside, filepath.Join(project, "escape")) == nil
+	os.Symlink(filepath.Join(project, "css"), filepath.Join(project, "inner"))
+
+	tables := []struct {
+		rel      string
+		expected string
+		symlink  bool
+	}{
+		{"css/main.css", filepath.Join(project, "css", "main.css"), false},
+		{"new/dir/page.html", filepath.Join(project, "new", "dir", "page.html"), false},
+		{"../outside/x.css", "", false},
+		{"/tmp/x.css", "", false},
+		{"escape/x.css", "", true},
+		{"escape/deeper/x.css", "", true},
+		{"inner/x.css", filepath.Join(project, "inner", "x.css"), true},
+	}
+	for _, table := range tables {
+		if table.symlink && !symlinks {
+			continue
+		}
+		result, err := SafePath(project, table.rel)
+		if result != table.expected || (table.expected == "") != (err != nil) {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s SafePath Failed: %q, expected %q got %q (%v)\n", red("[-]"), table.rel, table.expected, result, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s SafePath Passing: %q \n", green("[+]"), table.rel)
+		}
+	}
+}
+
+func TestAddNameSuffix(t *testing.T) {
+	long := strings.Repeat("b", 255-len(".png"))
+	tables := []struct {
+		rel      string
+		suffix   string
+		expected string
+	}{
+		{"js/main.js", "_02078351", "js/main_02078351.js"},
+		{"index.html", "_2", "index_2.html"},
+		{"imgs/" + long + ".png", "_8ff6170b", "imgs/" + long[:len(long)-9] + "_8ff6170b.png"},
+	}
+	for _, table := range tables {
+		result := AddNameSuffix(table.rel, table.suffix)
+		if result != table.expected || ValidatePath(result) != nil {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s AddNameSuffix Failed: %q, expected %q got %q\n", red("[-]"), table.rel, table.expected, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s AddNameSuffix Passing: %q \n", green("[+]"), table.rel)
+		}
+	}
+}
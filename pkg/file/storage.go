@@ -0,0 +1,480 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage 克隆结果的输出后端，name为使用/分隔的相对路径；
+// 克隆时页面、资源、清单和爬取进度都通过后端读写，继续或更新时直接读取后端中已有的项目
+type Storage interface {
+	// Create 创建或替换文件，写入的内容在Commit后才可见，Abort放弃写入
+	Create(name string) (StorageWriter, error)
+	// Open 打开文件读取
+	Open(name string) (io.ReadCloser, error)
+	// Stat 返回文件信息，文件不存在时返回fs.ErrNotExist
+	Stat(name string) (fs.FileInfo, error)
+	// List 按字典序返回dir下（包括子目录）所有文件的相对路径，dir为空表示全部文件
+	List(dir string) ([]string, error)
+	// Remove 删除文件，文件不存在时返回fs.ErrNotExist
+	Remove(name string) error
+}
+
+// StorageWriter 写入后端的文件，AtomicFile同样实现了该接口
+type StorageWriter interface {
+	io.Writer
+	Commit() error
+	Abort()
+}
+
+// DirStorage 将文件保存在本地目录中的后端，写入先落到临时文件再原子替换
+type DirStorage struct {
+	root string
+}
+
+// NewDirStorage 创建以root为根目录的文件系统后端，root为空表示当前工作目录
+func NewDirStorage(root string) *DirStorage {
+	// 转为绝对路径，之后工作目录变化不影响后端；失败时保留相对路径
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &DirStorage{root: root}
+}
+
+// Root 返回根目录
+func (s *DirStorage) Root() string {
+	return s.root
+}
+
+// Create 实现Storage接口
+func (s *DirStorage) Create(name string) (StorageWriter, error) {
+	target, err := SafePath(s.root, name)
+	if err != nil {
+		return nil, err
+	}
+	return CreateAtomic(target)
+}
+
+// Open 实现Storage接口
+func (s *DirStorage) Open(name string) (io.ReadCloser, error) {
+	target, err := SafePath(s.root, name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(target)
+}
+
+// Stat 实现Storage接口
+func (s *DirStorage) Stat(name string) (fs.FileInfo, error) {
+	target, err := SafePath(s.root, name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(target)
+}
+
+// List 实现Storage接口，跳过未完成写入的临时文件
+func (s *DirStorage) List(dir string) ([]string, error) {
+	base := s.root
+	if dir != "" {
+		var err error
+		if base, err = SafePath(s.root, dir); err != nil {
+			return nil, err
+		}
+	}
+	var names []string
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == base {
+			return fs.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || isTempFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+// Remove 实现Storage接口
+func (s *DirStorage) Remove(name string) error {
+	target, err := SafePath(s.root, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(target)
+}
+
+// MemoryStorage 将文件保存在内存中的后端，可以被多个goroutine同时使用
+type MemoryStorage struct {
+	mu    sync.Mutex
+	files map[string]memoryFile
+}
+
+// memoryFile 内存中的文件内容和修改时间
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStorage 创建空的内存后端
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: make(map[string]memoryFile)}
+}
+
+// Create 实现Storage接口
+func (s *MemoryStorage) Create(name string) (StorageWriter, error) {
+	if err := ValidatePath(name); err != nil {
+		return nil, err
+	}
+	return &memoryWriter{storage: s, name: name}, nil
+}
+
+// Open 实现Storage接口
+func (s *MemoryStorage) Open(name string) (io.ReadCloser, error) {
+	f, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// ReadFile 返回文件内容
+func (s *MemoryStorage) ReadFile(name string) ([]byte, error) {
+	f, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.data, nil
+}
+
+// Stat 实现Storage接口
+func (s *MemoryStorage) Stat(name string) (fs.FileInfo, error) {
+	f, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	return memoryFileInfo{name: path.Base(name), size: int64(len(f.data)), modTime: f.modTime}, nil
+}
+
+// List 实现Storage接口
+func (s *MemoryStorage) List(dir string) ([]string, error) {
+	prefix := ""
+	if dir != "" {
+		if err := ValidatePath(dir); err != nil {
+			return nil, err
+		}
+		prefix = dir + "/"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name := range s.files {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Remove 实现Storage接口
+func (s *MemoryStorage) Remove(name string) error {
+	if err := ValidatePath(name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// file 返回文件内容，不存在时返回fs.ErrNotExist
+func (s *MemoryStorage) file(name string) (memoryFile, error) {
+	if err := ValidatePath(name); err != nil {
+		return memoryFile{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[name]
+	if !ok {
+		return memoryFile{}, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+// memoryWriter 写入内存后端的文件，Commit时替换原内容
+type memoryWriter struct {
+	storage *MemoryStorage
+	name    string
+	buf     bytes.Buffer
+	done    bool
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *memoryWriter) Commit() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.storage.mu.Lock()
+	defer w.storage.mu.Unlock()
+	w.storage.files[w.name] = memoryFile{data: w.buf.Bytes(), modTime: time.Now()}
+	return nil
+}
+
+func (w *memoryWriter) Abort() {
+	w.done = true
+	w.buf.Reset()
+}
+
+// memoryFileInfo 实现fs.FileInfo接口
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) Mode() fs.FileMode  { return FileMode }
+func (i memoryFileInfo) ModTime() time.Time { return i.modTime }
+func (i memoryFileInfo) IsDir() bool        { return false }
+func (i memoryFileInfo) Sys() interface{}   { return nil }
+
+// ArchiveStorage 将文件打包为zip或tar.gz写入w的后端：
+// 文件先保存在内存中（不需要临时目录），Close时按WriteArchive的规则生成确定性的归档
+type ArchiveStorage struct {
+	*MemoryStorage
+	w      io.Writer
+	format string
+}
+
+// NewArchiveStorage 创建写入w的归档后端，format可选"zip"或"tar.gz"，使用完毕后必须调用Close
+func NewArchiveStorage(w io.Writer, format string) (*ArchiveStorage, error) {
+	if err := ValidateArchiveFormat(format); err != nil {
+		return nil, err
+	}
+	return &ArchiveStorage{MemoryStorage: NewMemoryStorage(), w: w, format: format}, nil
+}
+
+// Close 将所有文件打包写入w
+func (s *ArchiveStorage) Close() error {
+	if err := WriteStorageArchive(s.w, s.MemoryStorage, s.format); err != nil {
+		return fmt.Errorf("写入归档失败: %w", err)
+	}
+	return nil
+}
+
+// SubStorage 返回后端中dir目录的视图，name相对于dir，用于将项目直接写入后端中以ConfigID命名的目录
+func SubStorage(s Storage, dir string) Storage {
+	if local, ok := s.(*DirStorage); ok {
+		return NewDirStorage(filepath.Join(local.root, filepath.FromSlash(dir)))
+	}
+	return &subStorage{parent: s, dir: dir}
+}
+
+// subStorage 非本地后端中一个目录的视图
+type subStorage struct {
+	parent Storage
+	dir    string
+}
+
+// name 校验相对路径并返回其在父后端中的名称，保证不会逃出dir
+func (s *subStorage) name(name string) (string, error) {
+	if err := ValidatePath(name); err != nil {
+		return "", err
+	}
+	return s.dir + "/" + name, nil
+}
+
+// Create 实现Storage接口
+func (s *subStorage) Create(name string) (StorageWriter, error) {
+	full, err := s.name(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.parent.Create(full)
+}
+
+// Open 实现Storage接口
+func (s *subStorage) Open(name string) (io.ReadCloser, error) {
+	full, err := s.name(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.parent.Open(full)
+}
+
+// Stat 实现Storage接口
+func (s *subStorage) Stat(name string) (fs.FileInfo, error) {
+	full, err := s.name(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.parent.Stat(full)
+}
+
+// List 实现Storage接口，返回相对于dir的路径
+func (s *subStorage) List(dir string) ([]string, error) {
+	full := s.dir
+	if dir != "" {
+		var err error
+		if full, err = s.name(dir); err != nil {
+			return nil, err
+		}
+	}
+	names, err := s.parent.List(full)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, s.dir+"/")
+	}
+	return names, nil
+}
+
+// Remove 实现Storage接口
+func (s *subStorage) Remove(name string) error {
+	full, err := s.name(name)
+	if err != nil {
+		return err
+	}
+	return s.parent.Remove(full)
+}
+
+// WriteStorageFile 将r的内容写入后端中的name，写入完成后才替换原文件，返回写入的字节数
+func WriteStorageFile(s Storage, name string, r io.Reader) (int64, error) {
+	w, err := s.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		w.Abort()
+		return 0, err
+	}
+	if err := w.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ReadStorageFile 读取后端中文件的全部内容，文件不存在时返回fs.ErrNotExist
+func ReadStorageFile(s Storage, name string) ([]byte, error) {
+	r, err := s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// StorageSize 返回后端中所有文件的总大小
+func StorageSize(s Storage) (int64, error) {
+	names, err := s.List("")
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, name := range names {
+		info, err := s.Stat(name)
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// SyncToStorage 将本地目录srcDir中的文件写入后端的prefix目录下，
+// 后端prefix目录中本地已不存在的文件会被删除，使两者内容一致
+func SyncToStorage(dst Storage, prefix string, srcDir string) error {
+	local := NewDirStorage(srcDir)
+	names, err := local.List("")
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		target := path.Join(prefix, name)
+		keep[target] = true
+		if err := copyStorageFile(dst, target, local, name); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+	}
+
+	existing, err := dst.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range existing {
+		if keep[name] {
+			continue
+		}
+		if err := dst.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("删除 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SyncFromStorage 将后端prefix目录下的文件写入本地目录dstDir，用于在本地继续或更新之前的克隆
+func SyncFromStorage(src Storage, prefix string, dstDir string) error {
+	names, err := src.List(prefix)
+	if err != nil {
+		return err
+	}
+	local := NewDirStorage(dstDir)
+	for _, name := range names {
+		rel := strings.TrimPrefix(name, prefix+"/")
+		if err := copyStorageFile(local, rel, src, name); err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// copyStorageFile 将src中的文件复制到dst
+func copyStorageFile(dst Storage, dstName string, src Storage, srcName string) error {
+	r, err := src.Open(srcName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := dst.Create(dstName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Commit()
+}
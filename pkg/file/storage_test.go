@@ -0,0 +1,169 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestStorage(t *testing.T) {
+	archive, err := NewArchiveStorage(io.Discard, ArchiveZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	backends := map[string]Storage{
+		"dir":     NewDirStorage(t.TempDir()),
+		"memory":  NewMemoryStorage(),
+		"archive": archive,
+	}
+	for name, storage := range backends {
+		err := checkStorage(storage)
+		if err != nil {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Storage Failed: %s (%v)\n", red("[-]"), name, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Storage Passing: %s \n", green("[+]"), name)
+		}
+	}
+}
+
+// checkStorage 写入、读取、列出、删除文件，并与本地目录双向同步
+func checkStorage(storage Storage) error {
+	w, err := storage.Create("old/index.html")
+	if err != nil {
+		return err
+	}
+	w.Write([]byte("discarded"))
+	w.Abort()
+	if _, err := storage.Stat("old/index.html"); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("aborted write is visible: %v", err)
+	}
+	if _, err := storage.Create("../escape.html"); err == nil {
+		return fmt.Errorf("path outside storage accepted")
+	}
+
+	// 同步时删除本地已不存在的gone.png，保留其他目录中的文件
+	src, err := os.MkdirTemp("", "storage-src-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(src)
+	for _, name := range []string{"index.html", "css/main.css"} {
+		os.MkdirAll(filepath.Join(src, filepath.Dir(name)), 0700)
+		os.WriteFile(filepath.Join(src, name), []byte("content of "+name), 0600)
+	}
+	for _, name := range []string{"site/imgs/gone.png", "other/keep.txt"} {
+		w, err := storage.Create(name)
+		if err != nil {
+			return err
+		}
+		w.Write([]byte("old"))
+		if err := w.Commit(); err != nil {
+			return err
+		}
+	}
+	if err := SyncToStorage(storage, "site", src); err != nil {
+		return err
+	}
+	names, err := storage.List("")
+	if err != nil {
+		return err
+	}
+	expected := "other/keep.txt,site/css/main.css,site/index.html"
+	if result := strings.Join(names, ","); result != expected {
+		return fmt.Errorf("expected %s got %s", expected, result)
+	}
+	info, err := storage.Stat("site/css/main.css")
+	if err != nil || info.Size() != int64(len("content of css/main.css")) {
+		return fmt.Errorf("stat: %v", err)
+	}
+
+	dst, err := os.MkdirTemp("", "storage-dst-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dst)
+	if err := SyncFromStorage(storage, "site", dst); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "css", "main.css"))
+	if err != nil || !bytes.Equal(data, []byte("content of css/main.css")) {
+		return fmt.Errorf("sync back: %q (%v)", data, err)
+	}
+
+	if err := storage.Remove("other/keep.txt"); err != nil {
+		return err
+	}
+	if err := storage.Remove("other/keep.txt"); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing missing file: %v", err)
+	}
+	return nil
+}
+
+func TestArchiveStorage(t *testing.T) {
+	var buf bytes.Buffer
+	storage, err := NewArchiveStorage(&buf, ArchiveTarGz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := storage.Create("site/index.html")
+	w.Write([]byte("<html></html>"))
+	w.Commit()
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := archiveNames(buf.Bytes(), ArchiveTarGz)
+	if result := strings.Join(names, ","); err != nil || result != "site/,site/index.html" {
+		t.Errorf("unexpected archive entries %s (%v)", result, err)
+	}
+}
+
+func TestSubStorage(t *testing.T) {
+	backends := map[string]Storage{
+		"dir":    NewDirStorage(t.TempDir()),
+		"memory": NewMemoryStorage(),
+	}
+	for name, storage := range backends {
+		project := SubStorage(storage, "site")
+		_, err := WriteStorageFile(project, "css/main.css", strings.NewReader("body{}"))
+		if err == nil {
+			_, err = project.Create("../escape.html")
+			if err == nil {
+				err = fmt.Errorf("path outside project accepted")
+			} else {
+				err = nil
+			}
+		}
+		var names, inner []string
+		if err == nil {
+			names, err = storage.List("")
+		}
+		if err == nil {
+			inner, err = project.List("css")
+		}
+		size, _ := StorageSize(project)
+		if err != nil || strings.Join(names, ",") != "site/css/main.css" || strings.Join(inner, ",") != "css/main.css" || size != 6 {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s SubStorage Failed: %s, got %v %v size %d (%v)\n", red("[-]"), name, names, inner, size, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s SubStorage Passing: %s \n", green("[+]"), name)
+		}
+	}
+}
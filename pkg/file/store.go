@@ -0,0 +1,207 @@
+package file
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store 以内容的SHA-256为键的共享存储，多个项目中内容相同的资源只保存一份：
+// 项目中的文件与存储中的blob是同一文件的硬链接，无法创建硬链接时（如跨文件系统）各自保存一份，
+// 项目的manifest.json记录每个文件对应的blob，GC按登记的项目删除不再被引用的blob
+type Store struct {
+	root string
+}
+
+const (
+	// storeBlobs 存储中保存内容的目录，按哈希前两位分子目录
+	storeBlobs = "blobs"
+	// storeProjects 存储中登记项目的目录，每个文件记录一个项目的绝对路径
+	storeProjects = "projects"
+)
+
+// OpenStore 打开共享存储，目录不存在时创建
+func OpenStore(root string) (*Store, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{storeBlobs, storeProjects} {
+		if err := os.MkdirAll(filepath.Join(root, dir), DirMode); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{root: root}, nil
+}
+
+// Root 返回存储目录的绝对路径
+func (s *Store) Root() string {
+	return s.root
+}
+
+// blobPath 返回内容哈希对应的blob路径
+func (s *Store) blobPath(sum string) string {
+	return filepath.Join(s.root, storeBlobs, sum[:2], sum)
+}
+
+// Register 登记使用存储的项目，GC时读取其manifest.json中记录的blob
+func (s *Store) Register(projectPath string) error {
+	projectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return err
+	}
+	sum := sha1.Sum([]byte(projectPath))
+	_, err = WriteFileAtomic(filepath.Join(s.root, storeProjects, hex.EncodeToString(sum[:])), strings.NewReader(projectPath))
+	return err
+}
+
+// Link 将项目中的文件放入存储：存储中已有相同内容时项目文件替换为blob的硬链接，
+// 否则文件本身成为新的blob；sum为已知的内容哈希（可以为空），与blob已是同一文件时不再读取内容，
+// 返回文件内容的SHA-256
+func (s *Store) Link(projectPath string, rel string, sum string) (string, error) {
+	target, err := SafePath(projectPath, rel)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if len(sum) == sha256.Size*2 {
+		if blob, err := os.Stat(s.blobPath(sum)); err == nil && os.SameFile(info, blob) {
+			return sum, nil
+		}
+	}
+
+	// 按实际内容计算哈希，避免过期的记录使不同内容共用一个blob
+	sum, err = fileSHA256(target)
+	if err != nil {
+		return "", err
+	}
+	blob := s.blobPath(sum)
+	if err := os.MkdirAll(filepath.Dir(blob), DirMode); err != nil {
+		return "", err
+	}
+	err = os.Link(target, blob)
+	switch {
+	case err == nil:
+		return sum, nil
+	case errors.Is(err, fs.ErrExist):
+		existing, statErr := os.Stat(blob)
+		if statErr == nil && os.SameFile(info, existing) {
+			return sum, nil
+		}
+		return sum, replaceWithLink(blob, target)
+	default:
+		// 不支持硬链接时复制一份，项目仍通过manifest.json引用
+		return sum, copyToBlob(target, blob)
+	}
+}
+
+// replaceWithLink 将target原子替换为blob的硬链接，不支持硬链接时保留原文件
+func replaceWithLink(blob string, target string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	if err := os.Link(blob, tmp.Name()); err != nil {
+		return nil
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// copyToBlob 复制文件作为新的blob，blob已存在时不覆盖
+func copyToBlob(src string, blob string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := os.Stat(blob); err == nil {
+		return nil
+	}
+	_, err = WriteFileAtomic(blob, f)
+	return err
+}
+
+// fileSHA256 计算文件内容的SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GC 删除没有被任何已登记项目的manifest.json引用的blob，已删除的项目同时取消登记，
+// 返回删除的blob数量和释放的字节数（仍有项目文件硬链接时，磁盘空间在项目删除后才会释放）
+func (s *Store) GC() (int, int64, error) {
+	referenced := make(map[string]bool)
+	registrations, err := os.ReadDir(filepath.Join(s.root, storeProjects))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range registrations {
+		registration := filepath.Join(s.root, storeProjects, entry.Name())
+		projectPath, err := os.ReadFile(registration)
+		if err != nil {
+			continue
+		}
+		manifest, err := LoadManifest(string(projectPath))
+		if errors.Is(err, fs.ErrNotExist) {
+			if _, statErr := os.Stat(string(projectPath)); errors.Is(statErr, fs.ErrNotExist) {
+				fmt.Printf("项目已删除，取消登记: %s\n", projectPath)
+				os.Remove(registration)
+			}
+			continue
+		}
+		if err != nil {
+			// 无法确定引用了哪些blob时不做清理
+			return 0, 0, fmt.Errorf("读取项目 %s 的资源清单失败: %w", projectPath, err)
+		}
+		for _, sum := range manifest.Blobs {
+			referenced[sum] = true
+		}
+	}
+
+	removed := 0
+	var freed int64
+	err = filepath.WalkDir(filepath.Join(s.root, storeBlobs), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 正在写入的临时文件不是blob
+		if d.IsDir() || referenced[d.Name()] || isTempFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	return removed, freed, err
+}
@@ -0,0 +1,74 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestStore(t *testing.T) {
+	store, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects := []string{t.TempDir(), t.TempDir()}
+	contents := []map[string]string{
+		{"js/jquery.js": "jquery", "imgs/a.png": "A"},
+		{"js/jquery.js": "jquery", "imgs/a.png": "B"},
+	}
+	for i, projectPath := range projects {
+		manifest := NewManifest()
+		for rel, content := range contents[i] {
+			os.MkdirAll(filepath.Join(projectPath, filepath.Dir(rel)), 0755)
+			os.WriteFile(filepath.Join(projectPath, rel), []byte(content), 0644)
+			manifest.AddAsset("https://example.com/"+rel, rel)
+			// 过期的哈希不影响结果
+			sum, err := store.Link(projectPath, rel, "0000000000000000000000000000000000000000000000000000000000000000")
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest.SetBlob(rel, sum)
+		}
+		store.Register(projectPath)
+		manifest.Save(projectPath)
+	}
+
+	same := func(rel string) bool {
+		a, _ := os.Stat(filepath.Join(projects[0], rel))
+		b, _ := os.Stat(filepath.Join(projects[1], rel))
+		return os.SameFile(a, b)
+	}
+	shared, distinct := same("js/jquery.js"), !same("imgs/a.png")
+
+	// 删除第二个项目后，只有它引用的内容被清理
+	os.RemoveAll(projects[1])
+	removed, freed, err := store.GC()
+	data, _ := os.ReadFile(filepath.Join(projects[0], "imgs/a.png"))
+
+	tables := []struct {
+		name     string
+		result   interface{}
+		expected interface{}
+	}{
+		{"shared", shared, true},
+		{"distinct", distinct, true},
+		{"content", string(data), "A"},
+		{"gc removed", removed, 1},
+		{"gc freed", freed, int64(1)},
+		{"gc error", err, nil},
+	}
+	for _, table := range tables {
+		if table.result != table.expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Store Failed: %s, expected %v got %v\n", red("[-]"), table.name, table.expected, table.result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Store Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
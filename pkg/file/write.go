@@ -3,59 +3,50 @@ package file
 import (
 	"fmt"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/z-bool/go-website-clone/pkg/parser"
 )
 
-// CreateProject initializes the project directory and returns the path to the project
-// TODO make function more modular to obtain different html files
-func CreateProject(projectName string) string {
-	// current workin directory
-	path := currentDirectory()
-
-	// define project path
-	projectPath := path + "/" + projectName
-
-	// create base directory
-	err := os.MkdirAll(projectPath, 0777)
-	check(err)
-
-	// create CSS/JS/Image directories
-	createCSS(projectPath)
-	createJS(projectPath)
-	createIMG(projectPath)
-
-	// main inedx file
-	_, err = os.Create(projectPath + "/" + "index.html")
-	check(err)
-	// project path
-	return projectPath
+// CreateProject initializes the project directory under the current working directory
+// and returns the path to the project
+func CreateProject(projectName string) (string, error) {
+	return CreateProjectWithID("", projectName)
 }
 
-// CreateProjectWithID 使用指定的ID创建项目目录并返回项目路径
-func CreateProjectWithID(configID string) string {
-	// 当前工作目录
-	path := currentDirectory()
+// CreateProjectWithID 使用指定的ID在outputDir下创建项目目录并返回项目的绝对路径，outputDir为空表示当前工作目录；
+// ID必须是单个安全的目录名，包含路径分隔符或".."等会逃出outputDir的ID返回ErrUnsafePath
+func CreateProjectWithID(outputDir string, configID string) (string, error) {
+	if err := ValidateName(configID); err != nil {
+		return "", fmt.Errorf("无效的项目ID: %w", err)
+	}
 
 	// 使用ConfigID定义项目路径
-	projectPath := filepath.Join(path, configID)
+	projectPath, err := filepath.Abs(filepath.Join(outputDir, configID))
+	if err != nil {
+		return "", err
+	}
 
 	// 创建基础目录
-	err := os.MkdirAll(projectPath, 0777)
-	check(err)
+	if err := os.MkdirAll(projectPath, DirMode); err != nil {
+		return "", fmt.Errorf("创建项目目录失败: %w", err)
+	}
 
-	// 创建CSS/JS/Image目录
-	createCSS(projectPath)
-	createJS(projectPath)
-	createIMG(projectPath)
+	// 创建CSS/JS/Image/Font/Media等资源目录
+	if err := createAssetDirs(projectPath); err != nil {
+		return "", fmt.Errorf("创建资源目录失败: %w", err)
+	}
 
-	// 主index文件
-	_, err = os.Create(filepath.Join(projectPath, "index.html"))
-	check(err)
+	// 主index文件，已存在时保留原内容，以便继续中断的克隆
+	f, err := os.OpenFile(filepath.Join(projectPath, "index.html"), os.O_CREATE|os.O_WRONLY, FileMode)
+	if err != nil {
+		return "", fmt.Errorf("创建index.html失败: %w", err)
+	}
+	f.Close()
 
 	fmt.Printf("项目目录已创建: %s\n", projectPath)
-	return projectPath
+	return projectPath, nil
 }
 
 // GetFolderSize 计算文件夹的总大小（字节）
@@ -66,7 +57,8 @@ func GetFolderSize(folderPath string) (int64, error) {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() {
+		// 写入中断时遗留的临时文件不属于项目
+		if !d.IsDir() && !isTempFile(d.Name()) {
 			info, err := d.Info()
 			if err != nil {
 				return err
@@ -93,34 +85,12 @@ func CheckFolderSizeLimit(folderPath string, maxSize int64) (bool, int64, error)
 	return currentSize <= maxSize, currentSize, nil
 }
 
-// currentDirectory get the current working directory
-func currentDirectory() string {
-	path, err := os.Getwd()
-	check(err)
-	return path
-}
-
-// createCSS create a css directory in the current path
-func createCSS(path string) {
-	// create css directory
-	err := os.MkdirAll(path+"/"+"css", 0777)
-	check(err)
-}
-
-// createJS create a JS directory in the current path
-func createJS(path string) {
-	err := os.MkdirAll(path+"/"+"js", 0777)
-	check(err)
-}
-
-// createIMG create a image directory in the current path
-func createIMG(path string) {
-	err := os.MkdirAll(path+"/"+"imgs", 0777)
-	check(err)
-}
-
-func check(err error) {
-	if err != nil {
-		log.Println(err)
+// createAssetDirs create the css, js, image, font, media and misc directories in the current path
+func createAssetDirs(path string) error {
+	for _, dir := range parser.AssetDirs() {
+		if err := os.MkdirAll(filepath.Join(path, dir), DirMode); err != nil {
+			return err
+		}
 	}
+	return nil
 }
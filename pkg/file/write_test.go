@@ -0,0 +1,50 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+func TestCreateProjectWithID(t *testing.T) {
+	outputDir := t.TempDir()
+	projectPath, err := CreateProjectWithID(outputDir, "site")
+	expected := filepath.Join(outputDir, "site")
+	missing := ""
+	for _, name := range append(parser.AssetDirs(), "index.html") {
+		if _, statErr := os.Stat(filepath.Join(expected, name)); statErr != nil {
+			missing = name
+		}
+	}
+	if err != nil || projectPath != expected || missing != "" {
+		t.Error()
+		red := color.New(color.FgRed).SprintFunc()
+		fmt.Printf("%s CreateProjectWithID Failed: expected %s got %s, missing %q (%v)\n", red("[-]"), expected, projectPath, missing, err)
+
+	} else {
+		green := color.New(color.FgGreen).SprintFunc()
+		fmt.Printf("%s CreateProjectWithID Passing: %s \n", green("[+]"), projectPath)
+	}
+
+	// 输出目录是普通文件时返回错误，而不是只记录日志
+	blocked := filepath.Join(outputDir, "blocked")
+	os.WriteFile(blocked, []byte("file"), 0600)
+	if _, err := CreateProjectWithID(blocked, "site"); err == nil {
+		t.Error("expected error when output directory is a file")
+	}
+
+	// 会逃出输出目录的ID被拒绝，且不创建任何目录
+	for _, configID := range []string{"../escaped", "../../etc/x", "a/../../b", filepath.Join(outputDir, "abs"), "/tmp/abs", ".."} {
+		if _, err := CreateProjectWithID(outputDir, configID); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("expected ErrUnsafePath for %q, got %v", configID, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outputDir), "escaped")); err == nil {
+		t.Error("project created outside the output directory")
+	}
+}
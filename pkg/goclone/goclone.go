@@ -3,10 +3,14 @@ package goclone
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/z-bool/go-website-clone/pkg/crawler"
@@ -14,6 +18,7 @@ import (
 	"github.com/z-bool/go-website-clone/pkg/html"
 	"github.com/z-bool/go-website-clone/pkg/parser"
 	"github.com/z-bool/go-website-clone/pkg/utils"
+	"github.com/z-bool/go-website-clone/pkg/warc"
 )
 
 // Config 配置结构体，用于替代命令行参数
@@ -24,16 +29,93 @@ type Config struct {
 	UserAgent string
 	// ProxyString 代理连接字符串
 	ProxyString string
+	// RequestTimeout 单个页面或资源请求的超时时间，0表示只受context控制
+	RequestTimeout time.Duration
+	// InsecureSkipVerify 是否跳过TLS证书校验（只影响本次克隆的请求）
+	InsecureSkipVerify bool
 	// Cookies 预设的cookie列表
 	Cookies []string
-	// ConfigID 配置ID，使用UUID标识，用作保存文件夹名称
+	// ConfigID 配置ID，使用UUID标识，用作保存文件夹名称，不能包含路径分隔符或".."
 	ConfigID string
-	// MaxFolderSize 文件夹最大大小限制（字节）
+	// Resume 使用相同的ConfigID时从项目中保存的爬取进度继续，跳过已保存的页面和资源
+	Resume bool
+	// Update 重新访问已有项目，按ETag/Last-Modified发送条件请求，只下载有变化的页面和资源，
+	// 删除已不存在的内容并在CloneResult.Changes中返回变化，优先于Resume
+	Update bool
+	// MaxFolderSize 文件夹最大大小限制（字节），超出的页面和资源会被跳过并记录在CloneResult.Skipped
 	MaxFolderSize int64
+	// MaxFileSize 单个页面或资源的最大大小（字节），0表示不限制
+	MaxFileSize int64
+	// MaxScriptsSize 脚本文件的总大小配额（字节），计入MaxFolderSize，0表示不限制
+	MaxScriptsSize int64
+	// MaxStylesSize 样式表的总大小配额（字节），0表示不限制
+	MaxStylesSize int64
+	// MaxImagesSize 图片的总大小配额（字节），0表示不限制
+	MaxImagesSize int64
+	// MaxFontsSize 字体的总大小配额（字节），0表示不限制
+	MaxFontsSize int64
+	// MaxMediaSize 音视频的总大小配额（字节），0表示不限制
+	MaxMediaSize int64
+	// MaxDepth 从起始页面跟随同站链接的最大层数，0表示只克隆起始页面
+	MaxDepth int
+	// MaxPages 最多保存的页面数量，0表示不限制
+	MaxPages int
+	// AllowedDomains 除起始域名外允许抓取页面的域名，"*.example.com"表示包含子域名
+	AllowedDomains []string
+	// AssetDomains 允许下载资源的域名（如第三方CDN），为空表示不限制
+	AssetDomains []string
+	// IncludeSubdomains 是否允许起始域名及上述域名的子域名
+	IncludeSubdomains bool
+	// IncludePatterns 页面的路径和查询字符串需要匹配的规则，"re:"开头为正则，否则为glob；不限制资源
+	IncludePatterns []string
+	// ExcludePatterns 页面的路径和查询字符串命中后跳过的规则，格式同IncludePatterns
+	ExcludePatterns []string
+	// Concurrency 全局同时进行的请求数，0表示使用默认值16
+	Concurrency int
+	// PerHostConcurrency 每个主机同时进行的请求数，0表示只受全局限制
+	PerHostConcurrency int
+	// RequestsPerSecond 每个主机每秒最多发起的请求数，0表示不限制
+	RequestsPerSecond float64
+	// RandomDelay 每个请求发起前额外等待的最大随机时间，用于避免被目标站点封禁
+	RandomDelay time.Duration
+	// MaxAttempts 每个页面或资源的最大请求次数（包括首次），0表示默认3次，1表示不重试
+	MaxAttempts int
+	// RetryBaseDelay 第一次重试前的等待时间，之后指数增长并带随机抖动，0表示默认500ms
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay 两次请求之间的最长等待时间（也限制Retry-After），0表示默认30s
+	RetryMaxDelay time.Duration
+	// WARCPath 将克隆过程中的每个请求和响应写入的WARC 1.1文件，以.gz结尾时逐条压缩，为空表示不记录
+	WARCPath string
+	// WARCOnly 只生成WARC文件，不保留项目文件夹（需要同时设置WARCPath）
+	WARCOnly bool
+	// OutputDir 项目和归档的输出目录，为空表示当前工作目录；设置Storage时忽略
+	OutputDir string
+	// Storage 克隆结果的输出后端，nil表示OutputDir目录；file.NewDirStorage指定其他根目录，
+	// file.NewMemoryStorage保存在内存中，file.NewArchiveStorage打包写入io.Writer（使用后需Close）；
+	// 项目保存在后端中以ConfigID命名的目录下，页面、资源和爬取进度在克隆时直接写入后端，
+	// 继续或更新时直接读取后端中已有的项目，不需要本地临时目录
+	Storage file.Storage
+	// Renderer 保存页面前处理下载的HTML，nil表示原样保存；
+	// &crawler.JSRenderer{}使用内置的JavaScript引擎执行页面中的脚本，保存脚本构建后的DOM
+	Renderer crawler.Renderer
+	// CaptureAPI 记录渲染时页面脚本通过fetch或XMLHttpRequest请求的接口响应（需要设置Renderer），
+	// 保存在项目的api目录和recordings.json中，本地预览时按相同的路径返回
+	CaptureAPI bool
+	// StorePath 共享内容存储目录，多个项目中内容相同的资源只保存一份（硬链接），为空表示不使用；
+	// 不再被任何项目引用的内容可以通过GCStore清理
+	StorePath string
+	// Archive 克隆完成后将项目打包为与项目文件夹同名的归档，可选"zip"或"tar.gz"，为空表示不打包；
+	// 相同内容的项目生成完全相同的归档
+	Archive string
 	// AutoStartServer 是否自动启动本地服务器
 	AutoStartServer bool
 	// ClickTurnto 表单提交后跳转的URL地址
 	ClickTurnto string
+
+	// warcWriter 克隆期间打开的WARC文件
+	warcWriter *warc.Writer
+	// store 克隆期间打开的共享存储
+	store *file.Store
 }
 
 // GetProxyString 实现CrawlConfig接口
@@ -46,11 +128,119 @@ func (c *Config) GetUserAgent() string {
 	return c.UserAgent
 }
 
+// GetRequestTimeout 实现CrawlConfig接口
+func (c *Config) GetRequestTimeout() time.Duration {
+	return c.RequestTimeout
+}
+
+// GetInsecureSkipVerify 实现CrawlConfig接口
+func (c *Config) GetInsecureSkipVerify() bool {
+	return c.InsecureSkipVerify
+}
+
 // GetMaxFolderSize 实现CrawlConfig接口
 func (c *Config) GetMaxFolderSize() int64 {
 	return c.MaxFolderSize
 }
 
+// GetMaxDepth 实现CrawlConfig接口
+func (c *Config) GetMaxDepth() int {
+	return c.MaxDepth
+}
+
+// GetMaxPages 实现CrawlConfig接口
+func (c *Config) GetMaxPages() int {
+	return c.MaxPages
+}
+
+// GetScope 实现CrawlConfig接口
+func (c *Config) GetScope() crawler.ScopeConfig {
+	return crawler.ScopeConfig{
+		AllowedDomains:    c.AllowedDomains,
+		AssetDomains:      c.AssetDomains,
+		IncludeSubdomains: c.IncludeSubdomains,
+		IncludePatterns:   c.IncludePatterns,
+		ExcludePatterns:   c.ExcludePatterns,
+	}
+}
+
+// GetDownloadConfig 实现CrawlConfig接口
+func (c *Config) GetDownloadConfig() crawler.DownloadConfig {
+	return crawler.DownloadConfig{
+		Concurrency:        c.Concurrency,
+		PerHostConcurrency: c.PerHostConcurrency,
+		RequestsPerSecond:  c.RequestsPerSecond,
+		RandomDelay:        c.RandomDelay,
+	}
+}
+
+// GetRetryConfig 实现CrawlConfig接口
+func (c *Config) GetRetryConfig() crawler.RetryConfig {
+	return crawler.RetryConfig{
+		MaxAttempts: c.MaxAttempts,
+		BaseDelay:   c.RetryBaseDelay,
+		MaxDelay:    c.RetryMaxDelay,
+	}
+}
+
+// GetSizeLimits 实现CrawlConfig接口
+func (c *Config) GetSizeLimits() crawler.SizeLimits {
+	return crawler.SizeLimits{
+		MaxFileSize: c.MaxFileSize,
+		Scripts:     c.MaxScriptsSize,
+		Styles:      c.MaxStylesSize,
+		Images:      c.MaxImagesSize,
+		Fonts:       c.MaxFontsSize,
+		Media:       c.MaxMediaSize,
+	}
+}
+
+// GetResume 实现CrawlConfig接口
+func (c *Config) GetResume() bool {
+	return c.Resume
+}
+
+// GetUpdate 实现CrawlConfig接口
+func (c *Config) GetUpdate() bool {
+	return c.Update
+}
+
+// GetWARCWriter 实现CrawlConfig接口
+func (c *Config) GetWARCWriter() *warc.Writer {
+	return c.warcWriter
+}
+
+// outputStorage 返回输出后端，未设置时为OutputDir目录
+func (c *Config) outputStorage() file.Storage {
+	if c.Storage == nil {
+		return file.NewDirStorage(c.OutputDir)
+	}
+	return c.Storage
+}
+
+// localRoot 输出后端是本地目录时返回其根目录，项目直接在其中克隆
+func (c *Config) localRoot() (string, bool) {
+	if dir, ok := c.outputStorage().(*file.DirStorage); ok {
+		return dir.Root(), true
+	}
+	return "", false
+}
+
+// GetStore 实现CrawlConfig接口
+func (c *Config) GetStore() *file.Store {
+	return c.store
+}
+
+// GetRenderer 实现CrawlConfig接口
+func (c *Config) GetRenderer() crawler.Renderer {
+	return c.Renderer
+}
+
+// GetCaptureAPI 实现CrawlConfig接口
+func (c *Config) GetCaptureAPI() bool {
+	return c.CaptureAPI
+}
+
 // GetClickTurnto 实现CloneConfig接口
 func (c *Config) GetClickTurnto() string {
 	return c.ClickTurnto
@@ -60,10 +250,21 @@ func (c *Config) GetClickTurnto() string {
 type CloneResult struct {
 	// Success 是否成功
 	Success bool
-	// ProjectPaths 生成的项目路径列表
+	// ProjectPaths 生成的项目路径列表，输出后端不是本地目录时为项目在后端中的目录名
 	ProjectPaths []string
 	// FirstProject 第一个项目路径（用于服务器或打开）
 	FirstProject string
+	// ArchivePaths 设置Archive时生成的归档路径列表，与ProjectPaths一一对应，
+	// 输出后端不是本地目录时为归档在后端中的文件名
+	ArchivePaths []string
+	// Failures 下载失败的页面和资源及原因，单个资源失败不会中断克隆
+	Failures []crawler.Failure
+	// Skipped 因超出MaxFolderSize、MaxFileSize或分类配额没有保存的页面和资源，按Category区分类型
+	Skipped []crawler.Skip
+	// Attempts 每个页面和资源URL的请求次数（包括重试）
+	Attempts map[string]int
+	// Changes 更新模式下新增、修改和删除的页面及资源URL
+	Changes crawler.Changes
 	// ServerConfig 服务器配置信息（如果启动了服务器）
 	ServerConfig *utils.ServerConfig
 	// Error 错误信息
@@ -74,6 +275,7 @@ type CloneResult struct {
 func Clone(ctx context.Context, config *Config) *CloneResult {
 	result := &CloneResult{
 		ProjectPaths: make([]string, 0),
+		Attempts:     make(map[string]int),
 	}
 
 	if len(config.URLs) == 0 {
@@ -88,6 +290,47 @@ func Clone(ctx context.Context, config *Config) *CloneResult {
 
 	fmt.Printf("开始克隆 %d 个URL，配置ID: %s\n", len(config.URLs), config.ConfigID)
 
+	if config.WARCOnly && config.WARCPath == "" {
+		result.Error = fmt.Errorf("WARCOnly需要同时设置WARCPath")
+		return result
+	}
+	if config.Archive != "" {
+		if err := file.ValidateArchiveFormat(config.Archive); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	// 所有URL的请求和响应写入同一个WARC文件
+	if config.WARCPath != "" {
+		w, err := warc.Create(config.WARCPath)
+		if err != nil {
+			result.Error = fmt.Errorf("创建WARC文件失败: %w", err)
+			return result
+		}
+		config.warcWriter = w
+		defer func() {
+			config.warcWriter = nil
+			if err := w.Close(); err != nil {
+				fmt.Printf("关闭WARC文件失败: %v\n", err)
+			}
+		}()
+		fmt.Printf("请求和响应将记录到: %s\n", config.WARCPath)
+	}
+
+	// 只生成WARC时项目在临时目录中，其他后端无法使用硬链接，都不放入共享存储
+	_, local := config.localRoot()
+	if config.StorePath != "" && !config.WARCOnly && local {
+		store, err := file.OpenStore(config.StorePath)
+		if err != nil {
+			result.Error = fmt.Errorf("打开共享存储失败: %w", err)
+			return result
+		}
+		config.store = store
+		defer func() {
+			config.store = nil
+		}()
+	}
+
 	// 创建cookie jar
 	jar, err := cookiejar.New(&cookiejar.Options{})
 	if err != nil {
@@ -104,23 +347,49 @@ func Clone(ctx context.Context, config *Config) *CloneResult {
 	// 处理每个URL
 	for i, u := range config.URLs {
 		fmt.Printf("正在处理第 %d 个URL: %s\n", i+1, u)
-		projectPath, err := cloneURL(ctx, u, jar, config)
+		project, crawlResult, err := cloneURL(ctx, u, jar, config)
 		if err != nil {
 			result.Error = fmt.Errorf("克隆 %q 失败: %w", u, err)
 			return result
 		}
 
-		fmt.Printf("URL %s 克隆完成，项目路径: %s\n", u, projectPath)
-		result.ProjectPaths = append(result.ProjectPaths, projectPath)
+		result.Failures = append(result.Failures, crawlResult.Failures...)
+		result.Skipped = append(result.Skipped, crawlResult.Skipped...)
+		for link, attempts := range crawlResult.Attempts {
+			result.Attempts[link] += attempts
+		}
+		result.Changes.Added = append(result.Changes.Added, crawlResult.Changes.Added...)
+		result.Changes.Modified = append(result.Changes.Modified, crawlResult.Changes.Modified...)
+		result.Changes.Removed = append(result.Changes.Removed, crawlResult.Changes.Removed...)
+		if len(crawlResult.Failures) > 0 {
+			fmt.Printf("URL %s 有 %d 个资源下载失败\n", u, len(crawlResult.Failures))
+		}
+		if config.WARCOnly {
+			fmt.Printf("URL %s 克隆完成，已记录到: %s\n", u, config.WARCPath)
+			continue
+		}
+		output, archivePath, err := publishProject(config, project)
+		if err != nil {
+			result.Error = fmt.Errorf("保存 %q 失败: %w", u, err)
+			return result
+		}
+		fmt.Printf("URL %s 克隆完成，项目路径: %s\n", u, output)
+		result.ProjectPaths = append(result.ProjectPaths, output)
 		if result.FirstProject == "" {
-			result.FirstProject = projectPath
+			result.FirstProject = output
+		}
+		if archivePath != "" {
+			fmt.Printf("项目已打包到: %s\n", archivePath)
+			result.ArchivePaths = append(result.ArchivePaths, archivePath)
 		}
 	}
 
 	fmt.Println("所有URL克隆完成")
 
-	// 如果配置了自动启动服务器，启动本地服务器
-	if config.AutoStartServer && result.FirstProject != "" {
+	// 如果配置了自动启动服务器，启动本地服务器，项目不在本地目录中时无法预览
+	if config.AutoStartServer && result.FirstProject != "" && !local {
+		fmt.Println("输出后端不是本地目录，不启动本地服务器")
+	} else if config.AutoStartServer && result.FirstProject != "" {
 		fmt.Println("正在启动本地服务器...")
 		serverConfig, err := utils.StartServerWithConfig(result.FirstProject, config)
 		if err != nil {
@@ -135,11 +404,11 @@ func Clone(ctx context.Context, config *Config) *CloneResult {
 	return result
 }
 
-// cloneURL 克隆单个URL
-func cloneURL(ctx context.Context, targetURL string, jar *cookiejar.Jar, config *Config) (string, error) {
+// cloneURL 克隆单个URL，返回项目所在的后端
+func cloneURL(ctx context.Context, targetURL string, jar *cookiejar.Jar, config *Config) (file.Storage, *crawler.Result, error) {
 	isValid, isValidDomain := parser.ValidateURL(targetURL), parser.ValidateDomain(targetURL)
 	if !isValid && !isValidDomain {
-		return "", fmt.Errorf("URL %q 无效", targetURL)
+		return nil, nil, fmt.Errorf("URL %q 无效", targetURL)
 	}
 
 	finalURL := targetURL
@@ -147,20 +416,161 @@ func cloneURL(ctx context.Context, targetURL string, jar *cookiejar.Jar, config
 		finalURL = parser.CreateURL(targetURL)
 	}
 
-	// 使用ConfigID作为项目文件夹名称
-	projectPath := file.CreateProjectWithID(config.ConfigID)
+	// 使用ConfigID作为项目文件夹名称，只生成WARC时使用临时目录；
+	// 其他后端中的项目直接通过后端读写，与本地目录一样可以继续、更新或合并多个URL
+	var project file.Storage
+	root, local := config.localRoot()
+	switch {
+	case config.WARCOnly:
+		tmp, err := os.MkdirTemp("", "goclone-*")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(tmp)
+		project = file.NewDirStorage(tmp)
+	case local:
+		path, err := file.CreateProjectWithID(root, config.ConfigID)
+		if err != nil {
+			return nil, nil, err
+		}
+		project = file.NewDirStorage(path)
+	default:
+		if err := file.ValidateName(config.ConfigID); err != nil {
+			return nil, nil, fmt.Errorf("无效的项目ID: %w", err)
+		}
+		project = file.SubStorage(config.Storage, config.ConfigID)
+	}
 
 	// 执行爬取，传递配置对象以便进行大小检查
-	if err := crawler.CrawlWithConfig(ctx, finalURL, projectPath, jar, config); err != nil {
-		return "", fmt.Errorf("爬取失败: %w", err)
+	crawlResult, err := crawler.CrawlWithStorage(ctx, finalURL, project, jar, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("爬取失败: %w", err)
+	}
+	if config.WARCOnly {
+		return nil, crawlResult, nil
 	}
 
-	// 重构HTML链接
-	if err := html.LinkRestructure(projectPath); err != nil {
-		return "", fmt.Errorf("重构HTML链接失败: %w", err)
+	// 重构HTML链接，包括页面之间的链接
+	if err := html.LinkRestructureStorage(project, crawlResult.Manifest); err != nil {
+		return nil, nil, fmt.Errorf("重构HTML链接失败: %w", err)
 	}
 
-	return projectPath, nil
+	return project, crawlResult, nil
+}
+
+// publishProject 按Archive打包克隆完成的项目，返回项目和归档的位置：
+// 本地目录后端返回本地路径，其他后端返回在后端中的名称
+func publishProject(config *Config, project file.Storage) (string, string, error) {
+	storage := config.outputStorage()
+	root, local := config.localRoot()
+	output := config.ConfigID
+	if dir, ok := project.(*file.DirStorage); ok && local {
+		output = dir.Root()
+	}
+	if config.Archive == "" {
+		return output, "", nil
+	}
+
+	name := config.ConfigID + "." + config.Archive
+	if err := writeArchive(storage, name, project, config.Archive); err != nil {
+		return "", "", fmt.Errorf("打包项目失败: %w", err)
+	}
+	if local {
+		name = filepath.Join(root, name)
+	}
+	return output, name, nil
+}
+
+// ReplayWARC 将WARC文件还原为outputDir下以configID命名的项目文件夹（outputDir为空表示当前工作目录），
+// 目录结构和链接与在线克隆相同，返回项目路径
+func ReplayWARC(warcPath string, outputDir string, configID string) (string, *crawler.Result, error) {
+	if configID == "" {
+		configID = uuid.New().String()
+	}
+	projectPath, err := file.CreateProjectWithID(outputDir, configID)
+	if err != nil {
+		return "", nil, err
+	}
+	result, err := crawler.ReplayWARC(warcPath, projectPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("还原WARC失败: %w", err)
+	}
+	return projectPath, result, nil
+}
+
+// ExportSingleFile 将已完成的项目中的页面导出为单个自包含的HTML文件，
+// 样式表和脚本内联，图片和字体等资源转换为data URI，pagePath为空时导出index.html
+func ExportSingleFile(projectPath string, pagePath string, outputPath string) error {
+	if pagePath == "" {
+		pagePath = "index.html"
+	}
+	page, err := html.InlinePage(projectPath, pagePath)
+	if err != nil {
+		return fmt.Errorf("导出单文件HTML失败: %w", err)
+	}
+	if _, err := file.WriteFileAtomic(outputPath, strings.NewReader(page)); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", outputPath, err)
+	}
+	fmt.Printf("单文件HTML已导出到: %s\n", outputPath)
+	return nil
+}
+
+// ExportMHTML 按项目的manifest.json将起始页面、其他已保存的页面和所有已下载的资源打包为MHTML文件，
+// 每部分保留原始URL作为Content-Location，可以直接在支持.mhtml的浏览器中打开
+func ExportMHTML(projectPath string, outputPath string) error {
+	manifest, err := file.LoadManifest(projectPath)
+	if err != nil {
+		return fmt.Errorf("读取资源清单失败: %w", err)
+	}
+	f, err := file.CreateAtomic(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := html.WriteMHTML(f, projectPath, manifest); err != nil {
+		f.Abort()
+		return fmt.Errorf("导出MHTML失败: %w", err)
+	}
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", outputPath, err)
+	}
+	fmt.Printf("MHTML已导出到: %s\n", outputPath)
+	return nil
+}
+
+// WriteArchive 将已完成的项目打包写入w，format可选"zip"或"tar.gz"；
+// 条目按路径排序并使用固定的时间和权限，相同内容的项目总是生成字节相同的归档
+func WriteArchive(w io.Writer, projectPath string, format string) error {
+	if err := file.WriteArchive(w, projectPath, format); err != nil {
+		return fmt.Errorf("打包项目失败: %w", err)
+	}
+	return nil
+}
+
+// writeArchive 将项目打包为输出后端中的name，写入完成后才替换目标文件
+func writeArchive(storage file.Storage, name string, project file.Storage, format string) error {
+	w, err := storage.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := file.WriteStorageArchive(w, project, format); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Commit()
+}
+
+// GCStore 删除共享存储中不再被任何项目引用的内容，返回删除的数量和字节数
+func GCStore(storePath string) (int, int64, error) {
+	store, err := file.OpenStore(storePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, freed, err := store.GC()
+	if err != nil {
+		return removed, freed, fmt.Errorf("清理共享存储失败: %w", err)
+	}
+	fmt.Printf("共享存储已清理: 删除 %d 个文件, %d 字节\n", removed, freed)
+	return removed, freed, nil
 }
 
 // setupCookies 设置cookies
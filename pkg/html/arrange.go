@@ -2,20 +2,46 @@ package html
 
 import (
 	"fmt"
-	"io/ioutil"
+	"net/url"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/parser"
 )
 
 // arrange 重构HTML文件中的链接，将外部资源链接改为本地路径
-func arrange(projectDir string) error {
-	indexfile := projectDir + "/index.html"
+func arrange(project file.Storage) error {
+	return arrangePage(project, "index.html", "", nil)
+}
+
+// arrangePages 按资源清单重构所有已抓取页面中的资源链接和页面间链接，
+// 链接使用与下载时相同的本地文件名
+func arrangePages(project file.Storage, manifest *file.Manifest) error {
+	// 同一页面可能有多个URL（重定向前后），每个文件只处理一次
+	pageURLs := manifest.PagePaths()
+	pagePaths := make([]string, 0, len(pageURLs))
+	for pagePath := range pageURLs {
+		pagePaths = append(pagePaths, pagePath)
+	}
+	sort.Strings(pagePaths)
 
-	// 读取整个HTML文件
-	input, err := ioutil.ReadFile(indexfile)
+	for _, pagePath := range pagePaths {
+		if err := arrangePage(project, pagePath, pageURLs[pagePath], manifest); err != nil {
+			return fmt.Errorf("重构页面 %s 失败: %w", pagePath, err)
+		}
+	}
+	return nil
+}
+
+// arrangePage 重构单个页面，pagePath为项目内相对路径，pageURL用于解析相对链接
+func arrangePage(project file.Storage, pagePath string, pageURL string, manifest *file.Manifest) error {
+	// 读取整个HTML文件，页面路径可能来自manifest.json，后端会拒绝项目外的路径
+	input, err := file.ReadStorageFile(project, pagePath)
 	if err != nil {
 		return fmt.Errorf("读取HTML文件失败: %w", err)
 	}
@@ -26,44 +52,56 @@ func arrange(projectDir string) error {
 		return fmt.Errorf("解析HTML文档失败: %w", err)
 	}
 
-	// 替换CSS链接
-	doc.Find("link[rel='stylesheet']").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if exists && !strings.HasPrefix(href, "css/") {
-			file := filepath.Base(href)
-			// 移除查询参数
-			if idx := strings.Index(file, "?"); idx != -1 {
-				file = file[:idx]
-			}
-			s.SetAttr("href", "css/"+file)
+	// 子目录中的页面需要回到项目根目录引用资源
+	root := strings.Repeat("../", strings.Count(pagePath, "/"))
+
+	// 资源链接按<base href>解析，与抓取时一致
+	base, _ := url.Parse(pageURL)
+	if href, found := doc.Find("base[href]").Attr("href"); found && base != nil {
+		if ref, err := base.Parse(href); err == nil {
+			base = ref
 		}
-	})
-
-	// 替换JS链接
-	doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
-		src, exists := s.Attr("src")
-		if exists && !strings.HasPrefix(src, "js/") {
-			file := filepath.Base(src)
-			// 移除查询参数
-			if idx := strings.Index(file, "?"); idx != -1 {
-				file = file[:idx]
-			}
-			s.SetAttr("src", "js/"+file)
+	}
+	resolve := func(ref string) (string, bool) {
+		if manifest == nil {
+			return localAsset(project, root, ref)
 		}
-	})
-
-	// 替换图片链接
-	doc.Find("img[src]").Each(func(i int, s *goquery.Selection) {
-		src, exists := s.Attr("src")
-		if exists && !strings.HasPrefix(src, "imgs/") && !strings.HasPrefix(src, "data:") {
-			file := filepath.Base(src)
-			// 移除查询参数
-			if idx := strings.Index(file, "?"); idx != -1 {
-				file = file[:idx]
+		return mappedAsset(base, root, ref, manifest)
+	}
+
+	// 替换CSS/JS/图片/媒体等资源链接，只改写已下载到本地的资源
+	for _, asset := range AssetAttrs {
+		asset := asset
+		doc.Find(asset.Selector).Each(func(i int, s *goquery.Selection) {
+			value, exists := s.Attr(asset.Attr)
+			if !exists {
+				return
 			}
-			s.SetAttr("src", "imgs/"+file)
-		}
-	})
+			if !asset.Srcset {
+				if local, ok := resolve(value); ok {
+					s.SetAttr(asset.Attr, local)
+				}
+				return
+			}
+			candidates := parser.ParseSrcset(value)
+			for j := range candidates {
+				if local, ok := resolve(candidates[j].URL); ok {
+					candidates[j].URL = local
+				}
+			}
+			s.SetAttr(asset.Attr, parser.FormatSrcset(candidates))
+		})
+	}
+
+	// 替换指向已抓取页面的链接
+	if base != nil && manifest != nil {
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			if local, ok := localPageLink(base, pagePath, href, manifest); ok {
+				s.SetAttr("href", local)
+			}
+		})
+	}
 
 	// 获取修改后的HTML
 	html, err := doc.Html()
@@ -71,8 +109,70 @@ func arrange(projectDir string) error {
 		return fmt.Errorf("生成HTML失败: %w", err)
 	}
 
-	// 写回文件
-	return ioutil.WriteFile(indexfile, []byte(html), 0777)
+	// 写回文件，先写入临时文件再替换，中断时保留原页面
+	_, err = file.WriteStorageFile(project, pagePath, strings.NewReader(html))
+	return err
+}
+
+// localAsset 返回资源的本地路径，root为页面到项目根目录的相对前缀
+func localAsset(project file.Storage, root string, ref string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "blob:") {
+		return "", false
+	}
+	dir, file := parser.AssetPath(ref)
+	if dir == "" {
+		return "", false
+	}
+	// 被跳过的资源保留原始链接
+	if _, err := project.Stat(dir + "/" + file); err != nil {
+		return "", false
+	}
+	return root + dir + "/" + file, true
+}
+
+// mappedAsset 按下载记录返回资源的本地路径，未下载的资源保留原始链接
+func mappedAsset(base *url.URL, root string, ref string, manifest *file.Manifest) (string, bool) {
+	if base == nil || ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "blob:") {
+		return "", false
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	localPath, ok := manifest.Asset(u.String())
+	if !ok {
+		return "", false
+	}
+	if u.Fragment != "" {
+		localPath += "#" + u.EscapedFragment()
+	}
+	return root + localPath, true
+}
+
+// localPageLink 将页面中的链接解析为相对于当前页面的本地文件路径
+func localPageLink(base *url.URL, pagePath string, href string, manifest *file.Manifest) (string, bool) {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return "", false
+	}
+	ref, err := base.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	target, ok := manifest.Page(ref.String())
+	if !ok {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(path.Dir(pagePath), target)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if ref.Fragment != "" {
+		rel += "#" + ref.EscapedFragment()
+	}
+	return rel, true
 }
 
 var reSrc = regexp.MustCompile(`src\s*=\s*"(.+?)"`)
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 
 	"github.com/yosssi/gohtml"
+	"github.com/z-bool/go-website-clone/pkg/file"
 )
 
 // FormatHTML will formart any given string of HTML
@@ -15,7 +16,7 @@ func FormatHTML(filePath string) {
 	}
 	data := gohtml.Format(string(dat))
 	b := []byte(data)
-	error := ioutil.WriteFile(filePath, b, 0777)
+	error := ioutil.WriteFile(filePath, b, file.FileMode)
 	// handle this error
 	if error != nil {
 		// print it out
@@ -0,0 +1,259 @@
+package html
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+// InlinePage 将项目中的页面导出为单个自包含的HTML：样式表和脚本内联到页面中，
+// 图片、字体等资源（包括样式表url()和@import引用的资源）转换为data URI，
+// pagePath为项目内相对路径，项目中不存在的资源保留原始链接
+func InlinePage(projectDir string, pagePath string) (string, error) {
+	indexfile, err := file.SafePath(projectDir, pagePath)
+	if err != nil {
+		return "", err
+	}
+	input, err := os.ReadFile(indexfile)
+	if err != nil {
+		return "", fmt.Errorf("读取HTML文件失败: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(input)))
+	if err != nil {
+		return "", fmt.Errorf("解析HTML文档失败: %w", err)
+	}
+
+	in := &inliner{projectDir: projectDir}
+	pageDir := path.Dir(pagePath)
+
+	// 页面中的<style>和style属性可能通过url()引用资源
+	doc.Find("style").Each(func(i int, s *goquery.Selection) {
+		css := in.rewriteCSS(pageDir, []byte(s.Text()), nil)
+		s.SetHtml(escapeRawText(string(css), "style"))
+	})
+	doc.Find("[style]").Each(func(i int, s *goquery.Selection) {
+		value, _ := s.Attr("style")
+		s.SetAttr("style", string(in.rewriteCSS(pageDir, []byte(value), nil)))
+	})
+
+	// 样式表替换为<style>，保留media属性
+	doc.Find("link[rel='stylesheet'][href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		rel, _, ok := in.resolve(pageDir, href)
+		if !ok {
+			return
+		}
+		css, ok := in.stylesheet(rel, nil)
+		if !ok {
+			return
+		}
+		style := "<style"
+		if media, found := s.Attr("media"); found {
+			style += ` media="` + escapeAttr(media) + `"`
+		}
+		s.ReplaceWithHtml(style + ">" + escapeRawText(css, "style") + "</style>")
+	})
+
+	// 脚本内容直接写入<script>，去掉src和完整性校验
+	doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		rel, _, ok := in.resolve(pageDir, src)
+		if !ok {
+			return
+		}
+		data, ok := in.read(rel)
+		if !ok {
+			return
+		}
+		s.RemoveAttr("src")
+		s.RemoveAttr("integrity")
+		// SetHtml按原始文本解析脚本内容，SetText会转义引号和尖括号
+		s.SetHtml(escapeRawText(string(data), "script"))
+	})
+
+	// 预加载的资源已经内联，不再需要
+	doc.Find("link[rel='preload'], link[rel='prefetch'], link[rel='modulepreload']").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if _, _, ok := in.resolve(pageDir, href); ok {
+			s.Remove()
+		}
+	})
+
+	// 其余资源属性转换为data URI
+	for _, asset := range AssetAttrs {
+		asset := asset
+		if asset.Kind == "Css" || asset.Kind == "Js" || asset.Kind == "Preload" {
+			continue
+		}
+		doc.Find(asset.Selector).Each(func(i int, s *goquery.Selection) {
+			value, exists := s.Attr(asset.Attr)
+			if !exists {
+				return
+			}
+			if !asset.Srcset {
+				if uri, ok := in.dataURI(pageDir, value); ok {
+					s.SetAttr(asset.Attr, uri)
+				}
+				return
+			}
+			candidates := parser.ParseSrcset(value)
+			for j := range candidates {
+				if uri, ok := in.dataURI(pageDir, candidates[j].URL); ok {
+					candidates[j].URL = uri
+				}
+			}
+			s.SetAttr(asset.Attr, parser.FormatSrcset(candidates))
+		})
+	}
+
+	html, err := doc.Html()
+	if err != nil {
+		return "", fmt.Errorf("生成HTML失败: %w", err)
+	}
+	return html, nil
+}
+
+// inliner 读取项目中的资源，同一文件只读取一次
+type inliner struct {
+	projectDir string
+	cache      map[string][]byte
+}
+
+// resolve 将页面或样式表中的链接解析为项目内相对路径，dir为引用所在文件的目录；
+// 外部链接、data URI和项目中不存在的文件返回false
+func (in *inliner) resolve(dir string, ref string) (string, string, bool) {
+	candidates, fragment := localCandidates(dir, ref)
+	for _, rel := range candidates {
+		if _, ok := in.read(rel); ok {
+			return rel, fragment, true
+		}
+	}
+	return "", "", false
+}
+
+// read 读取项目中的文件，路径不安全或不是普通文件时返回false
+func (in *inliner) read(rel string) ([]byte, bool) {
+	if data, ok := in.cache[rel]; ok {
+		return data, true
+	}
+	target, err := file.SafePath(in.projectDir, rel)
+	if err != nil {
+		return nil, false
+	}
+	info, err := os.Stat(target)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, false
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return nil, false
+	}
+	if in.cache == nil {
+		in.cache = make(map[string][]byte)
+	}
+	in.cache[rel] = data
+	return data, true
+}
+
+// dataURI 将链接指向的项目文件转换为data URI，样式表中的引用同样内联，保留片段标识
+func (in *inliner) dataURI(dir string, ref string) (string, bool) {
+	rel, fragment, ok := in.resolve(dir, ref)
+	if !ok {
+		return "", false
+	}
+	data, _ := in.read(rel)
+	contentType := contentTypeOf(rel, data)
+	if contentType == "text/css" {
+		css, _ := in.stylesheet(rel, nil)
+		data = []byte(css)
+	}
+	uri := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	if fragment != "" {
+		uri += "#" + fragment
+	}
+	return uri, true
+}
+
+// stylesheet 返回样式表内容，其中的引用已转换为data URI，
+// importing为正在处理的样式表，用于跳过循环@import
+func (in *inliner) stylesheet(rel string, importing map[string]bool) (string, bool) {
+	data, ok := in.read(rel)
+	if !ok {
+		return "", false
+	}
+	if importing == nil {
+		importing = make(map[string]bool)
+	}
+	importing[rel] = true
+	defer delete(importing, rel)
+	return string(in.rewriteCSS(path.Dir(rel), data, importing)), true
+}
+
+// rewriteCSS 将CSS中引用的项目文件转换为data URI，被@import的样式表递归处理
+func (in *inliner) rewriteCSS(dir string, data []byte, importing map[string]bool) []byte {
+	return parser.RewriteCSSRefs(data, func(ref string) (string, bool) {
+		rel, fragment, ok := in.resolve(dir, ref)
+		if !ok || importing[rel] {
+			return "", false
+		}
+		if path.Ext(rel) != ".css" {
+			return in.dataURI(dir, ref)
+		}
+		css, _ := in.stylesheet(rel, importing)
+		uri := "data:text/css;base64," + base64.StdEncoding.EncodeToString([]byte(css))
+		if fragment != "" {
+			uri += "#" + fragment
+		}
+		return uri, true
+	})
+}
+
+// reEndTag 脚本或样式内容中的结束标签，大小写不敏感
+var reEndTag = regexp.MustCompile(`(?i)</(script|style)`)
+
+// escapeRawText 转义内联的脚本或样式中的结束标签，避免提前结束元素
+func escapeRawText(text string, tag string) string {
+	return reEndTag.ReplaceAllStringFunc(text, func(match string) string {
+		if !strings.EqualFold(match[2:], tag) {
+			return match
+		}
+		return `<\/` + match[2:]
+	})
+}
+
+// localCandidates 返回本地链接可能对应的项目内相对路径及片段标识，dir为引用所在文件的目录；
+// 改写后的本地链接未经转义，依次尝试原始路径和转义后的路径，外部链接和data URI返回nil
+func localCandidates(dir string, ref string) ([]string, string) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return nil, ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return nil, ""
+	}
+	raw, _, _ := strings.Cut(ref, "#")
+	raw, _, _ = strings.Cut(raw, "?")
+	var candidates []string
+	for _, p := range []string{raw, u.Path} {
+		rel := path.Join(dir, p)
+		if strings.HasPrefix(p, "/") {
+			rel = strings.TrimPrefix(p, "/")
+		}
+		candidates = append(candidates, rel)
+	}
+	return candidates, u.EscapedFragment()
+}
+
+// escapeAttr 转义写入HTML属性值的字符串
+func escapeAttr(value string) string {
+	return strings.NewReplacer("&", "&amp;", `"`, "&quot;", "<", "&lt;").Replace(value)
+}
@@ -0,0 +1,69 @@
+package html
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestInlinePage(t *testing.T) {
+	projectDir := t.TempDir()
+	files := map[string]string{
+		"blog/post.html": `<html><head>` +
+			`<link rel="stylesheet" href="../css/main.css" media="screen">` +
+			`<link rel="preload" href="../fonts/a.woff2" as="font">` +
+			`<script src="../js/app.js" integrity="sha384-x"></script>` +
+			`</head><body><img src="../imgs/a%20b.png" srcset="../imgs/a%20b.png 1x, https://cdn.example.com/b.png 2x">` +
+			`<div style="background:url('../imgs/a b.png')"></div><a href="../index.html">home</a></body></html>`,
+		"css/main.css":  `@import "theme.css";@font-face{src:url("../fonts/a.woff2")}`,
+		"css/theme.css": `@import "main.css";body{background:url(../imgs/missing.png)}`,
+		"js/app.js":     `document.write("</script>")`,
+		"imgs/a b.png":  "PNG",
+		"fonts/a.woff2": "WOFF",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(projectDir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644)
+	}
+
+	result, err := InlinePage(projectDir, "blog/post.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	png := "data:image/png;base64," + encode("PNG")
+	font := "data:font/woff2;base64," + encode("WOFF")
+	theme := "data:text/css;base64," + encode(`@import "main.css";body{background:url(../imgs/missing.png)}`)
+	tables := []struct {
+		name     string
+		expected string
+		present  bool
+	}{
+		{"stylesheet", `<style media="screen">@import "` + theme + `";@font-face{src:url("` + font + `")}</style>`, true},
+		{"script", `<script>document.write("<\/script>")</script>`, true},
+		{"img", `<img src="` + png + `" srcset="` + png + ` 1x, https://cdn.example.com/b.png 2x"/>`, true},
+		{"style attribute", `style="background:url(&#34;` + png + `&#34;)"`, true},
+		{"page link", `<a href="../index.html">`, true},
+		{"preload", `rel="preload"`, false},
+		{"integrity", `integrity`, false},
+	}
+	for _, table := range tables {
+		if strings.Contains(result, table.expected) != table.present {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s InlinePage Failed: %s, expected %q present=%v in %s\n", red("[-]"), table.name, table.expected, table.present, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s InlinePage Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
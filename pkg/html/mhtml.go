@@ -0,0 +1,272 @@
+package html
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/z-bool/go-website-clone/pkg/file"
+	"github.com/z-bool/go-website-clone/pkg/parser"
+)
+
+// WriteMHTML 将项目按资源清单打包为MHTML（multipart/related）写入w：
+// 第一部分为起始页面（index.html），之后是清单中的其他页面和每个已下载的资源，
+// 每部分的Content-Location为原始URL，页面和样式表中的本地链接（包括页面间链接）还原为原始URL，浏览器打开时无需改写链接
+func WriteMHTML(w io.Writer, projectDir string, manifest *file.Manifest) error {
+	pageURLs := manifest.PagePaths()
+	pageURL, ok := pageURLs["index.html"]
+	if !ok {
+		return fmt.Errorf("%s中没有起始页面", file.ManifestName)
+	}
+
+	// 本地路径到原始URL的映射，用于还原链接
+	assetURLs := make(map[string]string)
+	links := manifest.AssetURLs()
+	for _, link := range links {
+		localPath, _ := manifest.Asset(link)
+		assetURLs[localPath] = link
+	}
+	r := &restorer{pages: pageURLs, assets: assetURLs}
+
+	page, title, err := r.read(projectDir, "index.html")
+	if err != nil {
+		return err
+	}
+
+	mw := multipart.NewWriter(w)
+	contentType := mime.FormatMediaType("multipart/related", map[string]string{
+		"type":     "text/html",
+		"boundary": mw.Boundary(),
+	})
+	_, err = fmt.Fprintf(w, "From: <Saved by go-website-clone>\r\nSnapshot-Content-Location: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n",
+		pageURL, mime.QEncoding.Encode("utf-8", title), time.Now().Format(time.RFC1123Z), contentType)
+	if err != nil {
+		return err
+	}
+
+	if err := writePart(mw, pageURL, "text/html; charset=utf-8", bytes.NewReader(page)); err != nil {
+		return err
+	}
+	// 其他页面按本地路径排序，结果与清单的遍历顺序无关
+	pagePaths := make([]string, 0, len(pageURLs))
+	for pagePath := range pageURLs {
+		if pagePath != "index.html" {
+			pagePaths = append(pagePaths, pagePath)
+		}
+	}
+	sort.Strings(pagePaths)
+	for _, pagePath := range pagePaths {
+		page, _, err := r.read(projectDir, pagePath)
+		if err != nil {
+			// 保存失败或已删除的页面不打包
+			continue
+		}
+		if err := writePart(mw, pageURLs[pagePath], "text/html; charset=utf-8", bytes.NewReader(page)); err != nil {
+			return err
+		}
+	}
+	for _, link := range links {
+		localPath, _ := manifest.Asset(link)
+		data, err := readProjectFile(projectDir, localPath)
+		if err != nil {
+			// 下载失败或已删除的资源不打包
+			continue
+		}
+		contentType := contentTypeOf(localPath, data)
+		if contentType == "text/css" {
+			data = r.css(path.Dir(localPath), data)
+		}
+		if err := writePart(mw, link, contentType, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// restorer 将项目中的本地链接还原为原始URL
+type restorer struct {
+	// pages 页面本地路径到URL
+	pages map[string]string
+	// assets 资源本地路径到URL
+	assets map[string]string
+}
+
+// read 读取项目中的页面并还原其中的链接，同时返回页面标题
+func (r *restorer) read(projectDir string, pagePath string) ([]byte, string, error) {
+	data, err := readProjectFile(projectDir, pagePath)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("解析HTML文档失败: %w", err)
+	}
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	data, err = r.page(doc, pagePath)
+	return data, title, err
+}
+
+// page 还原页面中的资源链接和页面间链接，pagePath为项目内相对路径
+func (r *restorer) page(doc *goquery.Document, pagePath string) ([]byte, error) {
+	dir := path.Dir(pagePath)
+	for _, asset := range AssetAttrs {
+		asset := asset
+		doc.Find(asset.Selector).Each(func(i int, s *goquery.Selection) {
+			value, exists := s.Attr(asset.Attr)
+			if !exists {
+				return
+			}
+			if !asset.Srcset {
+				if link, ok := r.lookup(r.assets, dir, value); ok {
+					s.SetAttr(asset.Attr, link)
+				}
+				return
+			}
+			candidates := parser.ParseSrcset(value)
+			for j := range candidates {
+				if link, ok := r.lookup(r.assets, dir, candidates[j].URL); ok {
+					candidates[j].URL = link
+				}
+			}
+			s.SetAttr(asset.Attr, parser.FormatSrcset(candidates))
+		})
+	}
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if link, ok := r.lookup(r.pages, dir, href); ok {
+			s.SetAttr("href", link)
+		}
+	})
+	doc.Find("style").Each(func(i int, s *goquery.Selection) {
+		s.SetHtml(escapeRawText(string(r.css(dir, []byte(s.Text()))), "style"))
+	})
+	doc.Find("[style]").Each(func(i int, s *goquery.Selection) {
+		value, _ := s.Attr("style")
+		s.SetAttr("style", string(r.css(dir, []byte(value))))
+	})
+
+	html, err := doc.Html()
+	if err != nil {
+		return nil, fmt.Errorf("生成HTML失败: %w", err)
+	}
+	return []byte(html), nil
+}
+
+// css 还原样式表中的url()和@import引用，dir为样式表所在目录
+func (r *restorer) css(dir string, data []byte) []byte {
+	return parser.RewriteCSSRefs(data, func(ref string) (string, bool) {
+		return r.lookup(r.assets, dir, ref)
+	})
+}
+
+// lookup 返回本地链接对应的原始URL，保留片段标识
+func (r *restorer) lookup(urls map[string]string, dir string, ref string) (string, bool) {
+	candidates, fragment := localCandidates(dir, ref)
+	for _, rel := range candidates {
+		if link, ok := urls[rel]; ok {
+			if fragment != "" {
+				link += "#" + fragment
+			}
+			return link, true
+		}
+	}
+	return "", false
+}
+
+// writePart 写入一个MIME部分，文本内容使用quoted-printable编码，其他内容使用base64编码
+func writePart(mw *multipart.Writer, location string, contentType string, r io.Reader) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Location", location)
+	if isTextType(contentType) {
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+	} else {
+		header.Set("Content-Transfer-Encoding", "base64")
+	}
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	if isTextType(contentType) {
+		qp := quotedprintable.NewWriter(part)
+		if _, err := io.Copy(qp, r); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+	// base64每行76个字符
+	enc := base64.NewEncoder(base64.StdEncoding, &lineWriter{w: part, width: 76})
+	if _, err := io.Copy(enc, r); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// lineWriter 每写入width个字节插入一个CRLF
+type lineWriter struct {
+	w     io.Writer
+	width int
+	col   int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := l.width - l.col
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.col += n
+		p = p[n:]
+		if l.col == l.width {
+			if _, err := io.WriteString(l.w, "\r\n"); err != nil {
+				return written, err
+			}
+			l.col = 0
+		}
+	}
+	return written, nil
+}
+
+// readProjectFile 读取项目中的文件，路径必须位于项目目录内
+func readProjectFile(projectDir string, rel string) ([]byte, error) {
+	target, err := file.SafePath(projectDir, rel)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(target)
+}
+
+// contentTypeOf 按扩展名返回文件的媒体类型，未知扩展名时按内容检测，不包含参数
+func contentTypeOf(localPath string, data []byte) string {
+	contentType := mime.TypeByExtension(path.Ext(localPath))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	return contentType
+}
+
+// isTextType 文本类型的内容使用quoted-printable编码
+func isTextType(contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	return strings.HasPrefix(contentType, "text/") || contentType == "image/svg+xml" ||
+		strings.HasSuffix(contentType, "javascript") || strings.HasSuffix(contentType, "json")
+}
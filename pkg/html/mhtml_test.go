@@ -0,0 +1,98 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+func TestWriteMHTML(t *testing.T) {
+	projectDir := t.TempDir()
+	manifest := file.NewManifest()
+	manifest.AddPage("https://example.com/", "index.html")
+	manifest.AddPage("https://example.com/about", "about/index.html")
+	manifest.AddPage("https://example.com/gone", "gone/index.html")
+	manifest.AddAsset("https://cdn.example.net/main.css?v=1", "css/main.css")
+	manifest.AddAsset("https://example.com/logo.png", "imgs/logo.png")
+	manifest.AddAsset("https://example.com/missing.js", "js/missing.js")
+	files := map[string]string{
+		"index.html":       `<html><head><title>首页</title><link rel="stylesheet" href="css/main.css"></head><body><img src="imgs/logo.png#x"><a href="about/index.html">about</a><a href="https://other.com/">other</a></body></html>`,
+		"about/index.html": `<html><body><a href="../index.html#top">home</a><img src="../imgs/logo.png"></body></html>`,
+		"css/main.css":     `body{background:url("../imgs/logo.png")}`,
+		"imgs/logo.png":    "\x89PNG\r\n\x1a\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(projectDir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMHTML(&buf, projectDir, manifest); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	parts := make(map[string]string)
+	var order []string
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		// quoted-printable由multipart.Reader自动解码
+		data, _ := io.ReadAll(part)
+		location := part.Header.Get("Content-Location")
+		parts[location] = string(data)
+		order = append(order, location)
+	}
+
+	tables := []struct {
+		name     string
+		location string
+		expected string
+	}{
+		{"snapshot", "", "https://example.com/"},
+		{"page asset", "https://example.com/", `<img src="https://example.com/logo.png#x"/>`},
+		{"page stylesheet", "https://example.com/", `href="https://cdn.example.net/main.css?v=1"`},
+		{"page link", "https://example.com/", `<a href="https://example.com/about">`},
+		{"external link", "https://example.com/", `<a href="https://other.com/">`},
+		{"other page", "https://example.com/about", `<a href="https://example.com/#top">`},
+		{"other page asset", "https://example.com/about", `<img src="https://example.com/logo.png"/>`},
+		{"stylesheet", "https://cdn.example.net/main.css?v=1", `url("https://example.com/logo.png")`},
+		{"image", "https://example.com/logo.png", "iVBORw0KGgo="},
+	}
+	for _, table := range tables {
+		var ok bool
+		switch {
+		case table.location == "":
+			ok = msg.Header.Get("Snapshot-Content-Location") == table.expected && len(order) == 4 && order[0] == table.expected && order[1] == "https://example.com/about"
+		case table.name == "image":
+			ok = strings.TrimSpace(parts[table.location]) == table.expected
+		default:
+			ok = strings.Contains(parts[table.location], table.expected)
+		}
+		if !ok {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s WriteMHTML Failed: %s, expected %q in %q (%v)\n", red("[-]"), table.name, table.expected, parts[table.location], order)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s WriteMHTML Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
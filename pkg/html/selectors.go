@@ -0,0 +1,27 @@
+package html
+
+// AssetAttr describes an element attribute that references a downloadable resource
+type AssetAttr struct {
+	// Kind is a short label used in logs
+	Kind string
+	// Selector matches the elements holding the reference
+	Selector string
+	// Attr is the attribute holding the reference
+	Attr string
+	// Srcset marks attributes holding a list of image candidates
+	Srcset bool
+}
+
+// AssetAttrs lists every element attribute the crawler downloads and the rewriter localizes
+var AssetAttrs = []AssetAttr{
+	{Kind: "Css", Selector: "link[rel='stylesheet']", Attr: "href"},
+	{Kind: "Js", Selector: "script[src]", Attr: "src"},
+	{Kind: "Img", Selector: "img[src]", Attr: "src"},
+	{Kind: "Img", Selector: "img[srcset]", Attr: "srcset", Srcset: true},
+	{Kind: "Source", Selector: "source[srcset]", Attr: "srcset", Srcset: true},
+	{Kind: "Media", Selector: "source[src], video[src], audio[src], track[src]", Attr: "src"},
+	{Kind: "Poster", Selector: "video[poster]", Attr: "poster"},
+	{Kind: "Icon", Selector: "link[rel~='icon'], link[rel='apple-touch-icon'], link[rel='apple-touch-icon-precomposed']", Attr: "href"},
+	{Kind: "Preload", Selector: "link[rel='preload'], link[rel='prefetch'], link[rel='modulepreload']", Attr: "href"},
+	{Kind: "Manifest", Selector: "link[rel='manifest']", Attr: "href"},
+}
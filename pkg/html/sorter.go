@@ -1,8 +1,38 @@
 package html
 
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
 // LinkRestructure grabs all html files in project directory
 // reorganizes each file with local links (css js images)
+// projects with a manifest.json have every crawled page and link localized
 func LinkRestructure(projectDir string) error {
-	// Redirect JS/CSS/Img tags to the correct place :)
-	return arrange(projectDir)
+	manifest, err := file.LoadManifest(projectDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Redirect JS/CSS/Img tags to the correct place :)
+		return arrange(file.NewDirStorage(projectDir))
+	}
+	if err != nil {
+		return err
+	}
+	return LinkRestructureManifest(projectDir, manifest)
+}
+
+// LinkRestructureManifest reorganizes every page recorded in the manifest,
+// resources and links between pages point to the local copies it records
+func LinkRestructureManifest(projectDir string, manifest *file.Manifest) error {
+	return LinkRestructureStorage(file.NewDirStorage(projectDir), manifest)
+}
+
+// LinkRestructureStorage reorganizes every page recorded in the manifest,
+// the project is read from and written back to the given storage backend
+func LinkRestructureStorage(project file.Storage, manifest *file.Manifest) error {
+	if len(manifest.Pages) == 0 {
+		return arrange(project)
+	}
+	return arrangePages(project, manifest)
 }
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// file extension map for directing files to their proper directory in O(1) time
+var extensionDir = map[string]string{
+	".css":  "css",
+	".js":   "js",
+	".mjs":  "js",
+	".jpg":  "imgs",
+	".jpeg": "imgs",
+	".gif":  "imgs",
+	".png":  "imgs",
+	".svg":  "imgs",
+	".webp": "imgs",
+	".avif": "imgs",
+	".ico":  "imgs",
+	".bmp":  "imgs",
+	// fonts referenced from stylesheets
+	".woff":  "fonts",
+	".woff2": "fonts",
+	".ttf":   "fonts",
+	".otf":   "fonts",
+	".eot":   "fonts",
+	// audio, video and subtitles
+	".mp4":  "media",
+	".webm": "media",
+	".ogv":  "media",
+	".mov":  "media",
+	".mp3":  "media",
+	".ogg":  "media",
+	".wav":  "media",
+	".m4a":  "media",
+	".aac":  "media",
+	".vtt":  "media",
+	// web app manifests
+	".webmanifest": "misc",
+	".json":        "misc",
+}
+
+// mime type map for resources whose URL has no usable extension
+var mimeExtension = map[string]string{
+	"text/css":                      ".css",
+	"text/javascript":               ".js",
+	"application/javascript":        ".js",
+	"application/x-javascript":      ".js",
+	"application/ecmascript":        ".js",
+	"text/ecmascript":               ".js",
+	"image/jpeg":                    ".jpg",
+	"image/pjpeg":                   ".jpg",
+	"image/gif":                     ".gif",
+	"image/png":                     ".png",
+	"image/svg+xml":                 ".svg",
+	"image/webp":                    ".webp",
+	"image/avif":                    ".avif",
+	"image/x-icon":                  ".ico",
+	"image/vnd.microsoft.icon":      ".ico",
+	"image/bmp":                     ".bmp",
+	"font/woff":                     ".woff",
+	"font/woff2":                    ".woff2",
+	"font/ttf":                      ".ttf",
+	"font/otf":                      ".otf",
+	"application/font-woff":         ".woff",
+	"application/x-font-woff":       ".woff",
+	"application/font-woff2":        ".woff2",
+	"application/x-font-ttf":        ".ttf",
+	"application/x-font-otf":        ".otf",
+	"application/vnd.ms-fontobject": ".eot",
+	"video/mp4":                     ".mp4",
+	"video/webm":                    ".webm",
+	"video/ogg":                     ".ogv",
+	"video/quicktime":               ".mov",
+	"audio/mpeg":                    ".mp3",
+	"audio/ogg":                     ".ogg",
+	"audio/wav":                     ".wav",
+	"audio/x-wav":                   ".wav",
+	"audio/wave":                    ".wav",
+	"audio/mp4":                     ".m4a",
+	"audio/aac":                     ".aac",
+	"text/vtt":                      ".vtt",
+	"application/manifest+json":     ".webmanifest",
+	"application/json":              ".json",
+}
+
+var invalidChars = regexp.MustCompile(`[<>:"/\\|?*]`)
+
+// AssetDirs returns every directory resources are saved to inside a project
+func AssetDirs() []string {
+	return []string{"css", "js", "imgs", "fonts", "media", "misc"}
+}
+
+// AssetDir returns the project directory for a file extension, or an empty string
+func AssetDir(ext string) string {
+	/*
+		>>> .css
+		<<< css
+
+		>>> .woff2
+		<<< fonts
+
+		>>> .php
+		<<< ""
+	*/
+	return extensionDir[strings.ToLower(ext)]
+}
+
+// MIMEExtension returns the file extension for a Content-Type header, or an empty string
+func MIMEExtension(contentType string) string {
+	/*
+		>>> text/css; charset=utf-8
+		<<< .css
+
+		>>> image/svg+xml
+		<<< .svg
+
+		>>> text/html
+		<<< ""
+	*/
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return mimeExtension[strings.ToLower(strings.TrimSpace(mediaType))]
+}
+
+// URLBasename returns the sanitized file name of a URL without its extension
+func URLBasename(link string) string {
+	/*
+		>>> https://tesla.com/css/home?v=3
+		<<< home
+
+		>>> https://tesla.com/
+		<<< index
+	*/
+	u, err := url.Parse(link)
+	if err != nil {
+		return "unnamed_file"
+	}
+	base := path.Base(u.Path)
+	if base == "/" || base == "." {
+		return "index"
+	}
+	return sanitizeFilename(strings.TrimSuffix(base, path.Ext(base)))
+}
+
+// AssetPath returns the project directory and file name a resource URL is saved as,
+// or empty strings when the resource type is not supported
+func AssetPath(link string) (string, string) {
+	/*
+		>>> https://tesla.com/main.css?v=3
+		<<< css main.css
+
+		>>> https://tesla.com/static/Logo.PNG
+		<<< imgs Logo.png
+
+		>>> https://dribbble.com/css/home
+		<<< "" ""
+	*/
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", ""
+	}
+
+	// file base without the query string
+	base := path.Base(u.Path)
+	ext := strings.ToLower(URLExtension(base))
+	dir := AssetDir(ext)
+	if dir == "" {
+		return "", ""
+	}
+
+	return dir, URLBasename(link) + ext
+}
+
+// sanitizeFilename 清理文件名中的非法字符
+func sanitizeFilename(filename string) string {
+	// 移除Windows文件名中的非法字符
+	filename = invalidChars.ReplaceAllString(filename, "_")
+
+	// 确保文件名不为空
+	if filename == "" {
+		filename = "unnamed_file"
+	}
+
+	return filename
+}
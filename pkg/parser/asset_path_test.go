@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestAssetPath(t *testing.T) {
+	tables := []struct {
+		url      string
+		expected string
+	}{
+		{"https://tesla.com/main.css", "css/main.css"},
+		{"https://tesla.com/main.css?v=1.2", "css/main.css"},
+		{"https://tesla.com/static/Logo.PNG", "imgs/Logo.png"},
+		{"https://fonts.gstatic.com/roboto.woff2", "fonts/roboto.woff2"},
+		{"https://tesla.com/video/intro.mp4#t=10", "media/intro.mp4"},
+		{"https://tesla.com/site.webmanifest", "misc/site.webmanifest"},
+		{"https://tesla.com/a%3Cb%3E.js", "js/a_b_.js"},
+		{"https://dribbble.com/css/home", "/"},
+		{"https://tesla.com/login.php", "/"},
+	}
+	for _, table := range tables {
+		dir, name := AssetPath(table.url)
+		result := dir + "/" + name
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s AssetPath Failed: %s , expected %s got %s \n", red("[-]"), table.url, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s AssetPath Passing: %s \n", green("[+]"), table.url)
+		}
+	}
+}
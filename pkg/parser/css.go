@@ -0,0 +1,47 @@
+package parser
+
+import "regexp"
+
+var (
+	// url("a.png") / url('a.png') / url(a.png)
+	reCSSURL = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+	// @import "a.css" / @import 'a.css', the url() form is matched by reCSSURL
+	reCSSImport = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// RewriteCSSRefs replaces the url() and @import references of a stylesheet,
+// rewrite returns the new value of a reference or false to keep it unchanged
+func RewriteCSSRefs(data []byte, rewrite func(ref string) (string, bool)) []byte {
+	/*
+		>>> body{background:url(bg.png)} with bg.png -> ../imgs/bg.png
+		<<< body{background:url("../imgs/bg.png")}
+
+		>>> @import 'theme.css'; with theme.css -> theme.css
+		<<< @import "theme.css";
+	*/
+	replace := func(re *regexp.Regexp, build func(value string) string) {
+		data = re.ReplaceAllFunc(data, func(match []byte) []byte {
+			groups := re.FindSubmatch(match)
+			var ref string
+			for _, g := range groups[1:] {
+				if len(g) > 0 {
+					ref = string(g)
+					break
+				}
+			}
+			value, ok := rewrite(ref)
+			if !ok {
+				return match
+			}
+			return []byte(build(value))
+		})
+	}
+
+	replace(reCSSURL, func(value string) string {
+		return `url("` + value + `")`
+	})
+	replace(reCSSImport, func(value string) string {
+		return `@import "` + value + `"`
+	})
+	return data
+}
@@ -13,6 +13,9 @@ func URLExtension(URL string) string {
 		>>> https://tesla.com/main.css?Asf341
 		<<< ".css"
 
+		>>> https://fonts.gstatic.com/roboto.woff2
+		<<< ".woff2"
+
 		>>> https://dribbble.com/css/home
 		<<< ""
 	*/
@@ -25,8 +28,8 @@ func URLExtension(URL string) string {
 	if len(ext) > 5 {
 		// for every index and letter after the 0 index (ext[0] is ".", we want to keep that)
 		for i, char := range ext[1:] {
-			// if the unicode value of the char is not within the bounds alpha chars
-			if !('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') {
+			// if the unicode value of the char is not within the bounds alphanumeric chars
+			if !(('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') || ('0' <= char && char <= '9')) {
 				// reinitialize the ext to only include the characters for the extension and break
 				ext = ext[:i+1]
 				break
@@ -1,6 +1,12 @@
 package parser
 
-import "path"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+)
 
 // URLFilename returns the file name from a given url
 func URLFilename(filename string) string {
@@ -25,3 +31,48 @@ func PathFilename(givenPath string) string {
 	*/
 	return path.Base(givenPath)
 }
+
+// PagePath returns the project relative path a crawled page is saved to
+func PagePath(pageURL string) string {
+	/*
+		>>> https://tesla.com/
+		<<< index.html
+
+		>>> https://tesla.com/models/
+		<<< models/index.html
+
+		>>> https://tesla.com/about.html
+		<<< about.html
+
+		>>> https://tesla.com/list.php?page=2
+		<<< list_b941a131.html
+	*/
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "index.html"
+	}
+
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || p == "/" {
+		p = path.Join(p, "index.html")
+	}
+
+	ext := path.Ext(p)
+	if ext != ".html" && ext != ".htm" {
+		if ext == "" {
+			p = path.Join(p, "index.html")
+		} else {
+			// dynamic pages such as .php or .aspx are saved as html
+			p = strings.TrimSuffix(p, ext) + ".html"
+		}
+	}
+
+	// query string variants of the same page are kept apart
+	if u.RawQuery != "" {
+		sum := sha1.Sum([]byte(u.RawQuery))
+		ext = path.Ext(p)
+		p = strings.TrimSuffix(p, ext) + "_" + hex.EncodeToString(sum[:4]) + ext
+	}
+
+	return strings.TrimPrefix(p, "/")
+}
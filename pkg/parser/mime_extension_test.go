@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestMIMEExtension(t *testing.T) {
+	tables := []struct {
+		contentType string
+		expected    string
+	}{
+		{"text/css", ".css"},
+		{"text/css; charset=utf-8", ".css"},
+		{"Application/JavaScript", ".js"},
+		{"image/svg+xml", ".svg"},
+		{"font/woff2", ".woff2"},
+		{"application/manifest+json", ".webmanifest"},
+		{"text/html; charset=utf-8", ""},
+		{"application/octet-stream", ""},
+		{"", ""},
+	}
+	for _, table := range tables {
+		result := MIMEExtension(table.contentType)
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s MIMEExtension Failed: %s , expected %s got %s \n", red("[-]"), table.contentType, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s MIMEExtension Passing: %s \n", green("[+]"), table.contentType)
+		}
+	}
+}
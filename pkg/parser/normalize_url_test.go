@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tables := []struct {
+		url      string
+		expected string
+	}{
+		{"https://Example.com", "https://example.com/"},
+		{"https://example.com/about#team", "https://example.com/about"},
+		{"https://example.com/list?page=2", "https://example.com/list?page=2"},
+	}
+	for _, table := range tables {
+		result := NormalizeURL(table.url)
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s NormalizeURL Failed: %s , expected %s got %s \n", red("[-]"), table.url, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s NormalizeURL Passing: %s \n", green("[+]"), table.url)
+		}
+	}
+}
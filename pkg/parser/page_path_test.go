@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestPagePath(t *testing.T) {
+	tables := []struct {
+		url      string
+		expected string
+	}{
+		{"https://tesla.com", "index.html"},
+		{"https://tesla.com/", "index.html"},
+		{"https://tesla.com/models/", "models/index.html"},
+		{"https://tesla.com/docs/intro", "docs/intro/index.html"},
+		{"https://tesla.com/about.html", "about.html"},
+		{"https://tesla.com/login.php", "login.html"},
+		{"https://tesla.com/list.php?page=2", "list_b941a131.html"},
+		{"https://tesla.com/a/../../b", "b/index.html"},
+	}
+	for _, table := range tables {
+		result := PagePath(table.url)
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s PagePath Failed: %s , expected %s got %s \n", red("[-]"), table.url, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s PagePath Passing: %s \n", green("[+]"), table.url)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import "strings"
+
+// SrcsetCandidate is one image candidate of a srcset attribute
+type SrcsetCandidate struct {
+	URL        string
+	Descriptor string
+}
+
+// ParseSrcset splits a srcset attribute into its image candidates
+func ParseSrcset(srcset string) []SrcsetCandidate {
+	/*
+		>>> a.png 1x, b.png 2x
+		<<< [{a.png 1x} {b.png 2x}]
+
+		>>> /img/w_100,h_50/a.png 100w
+		<<< [{/img/w_100,h_50/a.png 100w}]
+	*/
+	var candidates []SrcsetCandidate
+	s := srcset
+	for {
+		// skip whitespace and separating commas
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			return candidates
+		}
+
+		// the URL runs until the next whitespace, trailing commas end the candidate
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end == -1 {
+			end = len(s)
+		}
+		link := s[:end]
+		s = s[end:]
+		if trimmed := strings.TrimRight(link, ","); trimmed != link {
+			candidates = append(candidates, SrcsetCandidate{URL: trimmed})
+			continue
+		}
+
+		// the descriptor runs until a comma outside of parentheses
+		depth, i := 0, 0
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth > 0 {
+					depth--
+				}
+			}
+			if s[i] == ',' && depth == 0 {
+				break
+			}
+		}
+		candidates = append(candidates, SrcsetCandidate{URL: link, Descriptor: strings.TrimSpace(s[:i])})
+		s = s[i:]
+	}
+}
+
+// FormatSrcset joins image candidates back into a srcset attribute
+func FormatSrcset(candidates []SrcsetCandidate) string {
+	parts := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Descriptor == "" {
+			parts = append(parts, c.URL)
+			continue
+		}
+		parts = append(parts, c.URL+" "+c.Descriptor)
+	}
+	return strings.Join(parts, ", ")
+}
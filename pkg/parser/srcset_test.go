@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tables := []struct {
+		srcset   string
+		expected string
+	}{
+		{"a.png", "a.png"},
+		{"a.png 1x, b.png 2x", "a.png 1x, b.png 2x"},
+		{"  a.png   480w,\n b.png 800w ", "a.png 480w, b.png 800w"},
+		{"/img/w_100,h_50/a.png 100w, /img/w_200,h_100/a.png 200w", "/img/w_100,h_50/a.png 100w, /img/w_200,h_100/a.png 200w"},
+		{"a.png 1x,b.png 2x", "a.png 1x, b.png 2x"},
+		{"", ""},
+	}
+	for _, table := range tables {
+		result := FormatSrcset(ParseSrcset(table.srcset))
+		expectedresult := table.expected
+		if result != expectedresult {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s ParseSrcset Failed: %q , expected %q got %q \n", red("[-]"), table.srcset, expectedresult, result)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s ParseSrcset Passing: %q \n", green("[+]"), table.srcset)
+		}
+	}
+}
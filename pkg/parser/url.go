@@ -2,6 +2,7 @@ package parser
 
 import (
 	"net/url"
+	"strings"
 
 	strutil "github.com/torden/go-strutil"
 )
@@ -69,3 +70,27 @@ func GetDomain(validurl string) string {
 	// hostname
 	return hostname
 }
+
+// NormalizeURL returns a canonical form of the URL used to identify pages
+func NormalizeURL(rawURL string) string {
+	/*
+		>>> https://Example.com
+		<<< https://example.com/
+
+		>>> https://example.com/about#team
+		<<< https://example.com/about
+	*/
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
@@ -14,6 +14,8 @@ func TestURLExtension(t *testing.T) {
 	}{
 		{"https://tesla.com/main.css", ".css"},
 		{"https://tesla.com/main.css?Asf341", ".css"},
+		{"https://fonts.gstatic.com/roboto.woff2", ".woff2"},
+		{"https://fonts.gstatic.com/roboto.woff2?v=3", ".woff2"},
 		{"https://dribbble.com/css/home", ""},
 	}
 	for _, table := range tables {
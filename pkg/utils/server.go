@@ -2,15 +2,19 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/z-bool/go-website-clone/pkg/file"
 )
 
 // CloneConfig 配置接口，避免循环导入
@@ -24,6 +28,8 @@ type ServerConfig struct {
 	Port        int
 	Host        string
 	ClickTurnto string
+	// Recordings 项目中记录的接口响应，预览时按相同的路径返回，nil表示没有记录
+	Recordings *file.Recordings
 }
 
 // FindAvailablePort 查找系统中可用的端口
@@ -63,6 +69,15 @@ func StartServerWithConfig(projectPath string, config CloneConfig) (*ServerConfi
 		serverConfig.ClickTurnto = config.GetClickTurnto()
 	}
 
+	// 克隆时记录了接口响应的项目，页面脚本离线请求同一接口时返回记录的响应
+	recordings, err := file.LoadRecordings(projectPath)
+	if err == nil {
+		serverConfig.Recordings = recordings
+		fmt.Printf("已加载 %d 个记录的接口响应\n", recordings.Len())
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("读取%s失败: %v\n", file.RecordingsName, err)
+	}
+
 	go startHTTPServerWithConfig(serverConfig)
 
 	// 等待服务器启动
@@ -74,12 +89,26 @@ func StartServerWithConfig(projectPath string, config CloneConfig) (*ServerConfi
 
 // startHTTPServerWithConfig 启动HTTP服务器，带配置支持
 func startHTTPServerWithConfig(config *ServerConfig) {
+	addr := fmt.Sprintf(":%d", config.Port)
+	fmt.Printf("服务器监听地址: %s\n", addr)
+
+	if err := http.ListenAndServe(addr, newHandler(config)); err != nil {
+		fmt.Printf("服务器启动失败: %v\n", err)
+	}
+}
+
+// newHandler 创建本地服务器的路由，记录的接口响应在所有路由之前返回，
+// 使/js/、/misc/等静态目录下的接口路径同样可以回放
+func newHandler(config *ServerConfig) http.Handler {
 	r := mux.NewRouter()
 
 	// 处理静态文件
 	r.PathPrefix("/css/").Handler(http.StripPrefix("/css/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "css")))))
 	r.PathPrefix("/js/").Handler(http.StripPrefix("/js/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "js")))))
 	r.PathPrefix("/imgs/").Handler(http.StripPrefix("/imgs/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "imgs")))))
+	r.PathPrefix("/fonts/").Handler(http.StripPrefix("/fonts/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "fonts")))))
+	r.PathPrefix("/media/").Handler(http.StripPrefix("/media/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "media")))))
+	r.PathPrefix("/misc/").Handler(http.StripPrefix("/misc/", http.FileServer(http.Dir(filepath.Join(config.ProjectPath, "misc")))))
 
 	// 处理表单提交
 	r.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
@@ -91,12 +120,17 @@ func startHTTPServerWithConfig(config *ServerConfig) {
 		serveModifiedHTMLWithConfig(w, r, config)
 	})
 
-	addr := fmt.Sprintf(":%d", config.Port)
-	fmt.Printf("服务器监听地址: %s\n", addr)
-
-	if err := http.ListenAndServe(addr, r); err != nil {
-		fmt.Printf("服务器启动失败: %v\n", err)
-	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		filePath := req.URL.Path
+		if filePath == "/" {
+			filePath = "/index.html"
+		}
+		_, err := os.Stat(filepath.Join(config.ProjectPath, filePath))
+		if serveRecording(w, req, config, os.IsNotExist(err)) {
+			return
+		}
+		r.ServeHTTP(w, req)
+	})
 }
 
 // serveModifiedHTML 提供修改后的HTML文件
@@ -404,3 +438,33 @@ func serveModifiedHTMLWithConfig(w http.ResponseWriter, r *http.Request, config
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(modifiedContent))
 }
+
+// serveRecording 返回请求对应的记录的接口响应，没有记录时返回false；
+// 项目中有同名文件时只返回查询参数也相同的记录，避免页面被同一路径的接口响应覆盖
+func serveRecording(w http.ResponseWriter, r *http.Request, config *ServerConfig, fileMissing bool) bool {
+	if config.Recordings == nil {
+		return false
+	}
+	recording, ok := config.Recordings.Lookup(r.Method, r.URL.RequestURI())
+	if !ok {
+		return false
+	}
+	if !fileMissing {
+		if u, err := url.Parse(recording.URL); err != nil || u.RequestURI() != r.URL.RequestURI() {
+			return false
+		}
+	}
+
+	body, err := os.ReadFile(filepath.Join(config.ProjectPath, filepath.FromSlash(recording.Path)))
+	if err != nil {
+		http.Error(w, "无法读取记录的响应", http.StatusInternalServerError)
+		return true
+	}
+	fmt.Printf("返回记录的接口响应: %s -> %s\n", r.URL.RequestURI(), recording.URL)
+	if recording.ContentType != "" {
+		w.Header().Set("Content-Type", recording.ContentType)
+	}
+	w.WriteHeader(recording.Status)
+	w.Write(body)
+	return true
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+func TestServeRecordings(t *testing.T) {
+	projectPath := t.TempDir()
+	os.MkdirAll(filepath.Join(projectPath, "js"), file.DirMode)
+	os.WriteFile(filepath.Join(projectPath, "index.html"), []byte("<html><body>home</body></html>"), file.FileMode)
+	os.WriteFile(filepath.Join(projectPath, "js", "app.js"), []byte("var app;"), file.FileMode)
+
+	recordings := file.NewRecordings()
+	for link, body := range map[string]string{
+		"https://example.com/api/items":          `["a"]`,
+		"https://example.com/js/config.json?v=2": `{"v":2}`,
+		"https://example.com/js/app.js?v=2":      `var recorded;`,
+	} {
+		if _, err := recordings.Add(projectPath, "", link, 200, "application/json", []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := newHandler(&ServerConfig{ProjectPath: projectPath, Recordings: recordings})
+
+	tables := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{"api", "/api/items", `["a"]`},
+		{"api under static prefix", "/js/config.json?v=2", `{"v":2}`},
+		{"api under static prefix with other query", "/js/config.json?v=3", `{"v":2}`},
+		// 项目中的文件只被查询参数也相同的记录覆盖
+		{"static file", "/js/app.js", "var app;"},
+		{"static file exact recording", "/js/app.js?v=2", "var recorded;"},
+		{"page", "/", "home"},
+	}
+	for _, table := range tables {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", table.target, nil))
+		body, _ := io.ReadAll(w.Result().Body)
+		if !strings.Contains(string(body), table.expected) {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s ServeRecordings Failed: %s, expected %s got %s\n", red("[-]"), table.name, table.expected, body)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s ServeRecordings Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record 读取到的一条记录，Content只在下一次调用Reader.Next之前有效
+type Record struct {
+	Header  Header
+	Content io.Reader
+}
+
+// Type 返回记录类型（WARC-Type）
+func (r *Record) Type() string {
+	return r.Header.Get(FieldType)
+}
+
+// TargetURI 返回记录对应的URL（WARC-Target-URI），兼容旧版本中带尖括号的写法
+func (r *Record) TargetURI() string {
+	return strings.Trim(r.Header.Get(FieldTargetURI), "<>")
+}
+
+// Reader 顺序读取WARC文件中的记录，自动识别gzip压缩
+type Reader struct {
+	br      *bufio.Reader
+	content *io.LimitedReader
+}
+
+// NewReader 创建读取r的Reader，r以gzip魔数开头时按（多成员）gzip解压
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &Reader{br: br}, nil
+}
+
+// Next 返回下一条记录，没有更多记录时返回io.EOF；上一条记录未读完的内容会被跳过
+func (r *Reader) Next() (*Record, error) {
+	if r.content != nil {
+		if _, err := io.Copy(io.Discard, r.content); err != nil {
+			return nil, err
+		}
+		r.content = nil
+		// 记录之间以两个CRLF分隔
+		for i := 0; i < 2; i++ {
+			if line, err := r.readLine(); err != nil || line != "" {
+				return nil, fmt.Errorf("WARC记录结尾格式错误: %q %v", line, err)
+			}
+		}
+	}
+
+	version, err := r.readLine()
+	for err == nil && version == "" {
+		version, err = r.readLine()
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) && version == "" {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/1.") {
+		return nil, fmt.Errorf("不支持的WARC版本: %q", version)
+	}
+
+	var header Header
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("读取WARC记录头失败: %w", err)
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("WARC记录头格式错误: %q", line)
+		}
+		header = append(header, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	length, err := strconv.ParseInt(header.Get(FieldContentLength), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("WARC记录缺少有效的Content-Length: %q", header.Get(FieldContentLength))
+	}
+	r.content = &io.LimitedReader{R: r.br, N: length}
+	return &Record{Header: header, Content: r.content}, nil
+}
+
+// readLine 读取一行并去掉结尾的CRLF
+func (r *Reader) readLine() (string, error) {
+	line, err := r.br.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return line, err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
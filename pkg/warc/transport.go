@@ -0,0 +1,185 @@
+package warc
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Transport 将经过的每个请求和响应写入WARC文件，响应体在被读完或关闭时写入，
+// 读取过程中先缓存到临时文件，不会占用大量内存
+type Transport struct {
+	// Writer 写入记录的WARC Writer
+	Writer *Writer
+	// Transport 实际发送请求的传输层，nil表示http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// RoundTrip 实现http.RoundTripper接口，没有收到响应的请求不会被记录
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	date := time.Now()
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	spool, err := os.CreateTemp("", "warc-*.tmp")
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("创建WARC临时文件失败: %w", err)
+	}
+	// 临时文件依次保存响应头和响应体，即response记录的完整内容
+	if err := writeResponseHead(spool, resp); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = &recordingBody{
+		body:    resp.Body,
+		empty:   resp.Body == http.NoBody || resp.ContentLength == 0 || req.Method == http.MethodHead,
+		spool:   spool,
+		payload: newDigest(),
+		record: func(spool *os.File, payloadDigest string, truncated bool) error {
+			return t.writeExchange(req, resp, date, spool, payloadDigest, truncated)
+		},
+	}
+	return resp, nil
+}
+
+// writeExchange 写入一对response和request记录
+func (t *Transport) writeExchange(req *http.Request, resp *http.Response, date time.Time, spool *os.File, payloadDigest string, truncated bool) error {
+	responseID := NewRecordID()
+	header := Header{
+		{Name: FieldType, Value: TypeResponse},
+		{Name: FieldRecordID, Value: responseID},
+		{Name: FieldDate, Value: FormatDate(date)},
+		{Name: FieldTargetURI, Value: req.URL.String()},
+		{Name: FieldContentType, Value: ContentTypeResponse},
+		{Name: FieldPayloadDigest, Value: payloadDigest},
+	}
+	if truncated {
+		header.Set(FieldTruncated, "unspecified")
+	}
+	if err := t.Writer.WriteRecord(header, spool); err != nil {
+		return err
+	}
+
+	var request bytes.Buffer
+	if err := writeRequest(&request, req); err != nil {
+		return err
+	}
+	return t.Writer.WriteRecord(Header{
+		{Name: FieldType, Value: TypeRequest},
+		{Name: FieldDate, Value: FormatDate(date)},
+		{Name: FieldTargetURI, Value: req.URL.String()},
+		{Name: FieldConcurrentTo, Value: responseID},
+		{Name: FieldContentType, Value: ContentTypeRequest},
+	}, bytes.NewReader(request.Bytes()))
+}
+
+// writeRequest 按HTTP/1.1格式写入请求行、请求头和可以重建的请求体
+func writeRequest(w io.Writer, req *http.Request) error {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+	if err := req.Header.Write(w); err != nil {
+		return err
+	}
+	io.WriteString(w, "\r\n")
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// writeResponseHead 按HTTP/1.1格式写入状态行和响应头；
+// 响应体已去掉分块传输编码，自动解压的响应同时去掉Content-Encoding和Content-Length
+func writeResponseHead(w io.Writer, resp *http.Response) error {
+	header := resp.Header.Clone()
+	header.Del("Transfer-Encoding")
+	if resp.Uncompressed {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+	}
+	if resp.ContentLength >= 0 && header.Get("Content-Length") == "" && !resp.Uncompressed {
+		header.Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if _, err := fmt.Fprintf(w, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status); err != nil {
+		return err
+	}
+	if err := header.Write(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// recordingBody 读取响应体时同时写入临时文件，读完或关闭时写入WARC记录，
+// 未读完就关闭的响应标记为WARC-Truncated
+type recordingBody struct {
+	body    io.ReadCloser
+	empty   bool
+	spool   *os.File
+	payload hash.Hash
+	record  func(spool *os.File, payloadDigest string, truncated bool) error
+
+	once sync.Once
+	err  error
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		if _, werr := b.spool.Write(p[:n]); werr != nil && b.err == nil {
+			b.err = werr
+		}
+		b.payload.Write(p[:n])
+	}
+	if err == io.EOF {
+		b.finish(false)
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.finish(!b.empty)
+	return err
+}
+
+// finish 写入记录并删除临时文件，只执行一次；记录失败只打印日志，不影响克隆
+func (b *recordingBody) finish(truncated bool) {
+	b.once.Do(func() {
+		defer os.Remove(b.spool.Name())
+		defer b.spool.Close()
+		err := b.err
+		if err == nil {
+			_, err = b.spool.Seek(0, io.SeekStart)
+		}
+		if err == nil {
+			err = b.record(b.spool, formatDigest(b.payload), truncated)
+		}
+		if err != nil {
+			fmt.Printf("写入WARC记录失败: %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+// Package warc 读写WARC 1.1格式的归档文件，用于记录克隆过程中的每个请求和响应
+package warc
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version WARC格式版本
+const Version = "WARC/1.1"
+
+// 记录类型
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// 常用的头字段
+const (
+	FieldType          = "WARC-Type"
+	FieldRecordID      = "WARC-Record-ID"
+	FieldDate          = "WARC-Date"
+	FieldTargetURI     = "WARC-Target-URI"
+	FieldConcurrentTo  = "WARC-Concurrent-To"
+	FieldBlockDigest   = "WARC-Block-Digest"
+	FieldPayloadDigest = "WARC-Payload-Digest"
+	FieldTruncated     = "WARC-Truncated"
+	FieldFilename      = "WARC-Filename"
+	FieldContentType   = "Content-Type"
+	FieldContentLength = "Content-Length"
+)
+
+// HTTP请求和响应记录的Content-Type
+const (
+	ContentTypeRequest  = "application/http;msgtype=request"
+	ContentTypeResponse = "application/http;msgtype=response"
+	ContentTypeFields   = "application/warc-fields"
+)
+
+// Field 单个头字段
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header 按写入顺序保存的记录头，字段名不区分大小写
+type Header []Field
+
+// Get 返回字段的值，不存在时返回空字符串
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set 设置字段的值，已存在时替换第一个同名字段并删除其余同名字段
+func (h *Header) Set(name string, value string) {
+	found := false
+	fields := (*h)[:0]
+	for _, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			if found {
+				continue
+			}
+			found = true
+			f.Value = value
+		}
+		fields = append(fields, f)
+	}
+	if !found {
+		fields = append(fields, Field{Name: name, Value: value})
+	}
+	*h = fields
+}
+
+// NewRecordID 生成新的记录ID
+func NewRecordID() string {
+	return "<urn:uuid:" + uuid.New().String() + ">"
+}
+
+// FormatDate 按WARC 1.1格式（UTC，可带小数秒）格式化时间
+func FormatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.999999Z")
+}
+
+// newDigest 返回计算WARC摘要使用的哈希
+func newDigest() hash.Hash {
+	return sha1.New()
+}
+
+// formatDigest 将哈希结果格式化为"sha1:BASE32"
+func formatDigest(h hash.Hash) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,117 @@
+package warc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestWriterReader(t *testing.T) {
+	tables := []struct {
+		name     string
+		compress bool
+	}{
+		{"plain", false},
+		{"gzip", true},
+	}
+	for _, table := range tables {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, table.compress)
+		w.WriteInfo("test.warc", Header{{Name: "software", Value: "test"}})
+		w.WriteRecord(Header{
+			{Name: FieldType, Value: TypeResponse},
+			{Name: FieldTargetURI, Value: "https://example.com/"},
+			{Name: FieldContentType, Value: ContentTypeResponse},
+		}, strings.NewReader("HTTP/1.1 200 OK\r\n\r\nhello"))
+
+		r, err := NewReader(&buf)
+		var types, contents []string
+		for err == nil {
+			var record *Record
+			record, err = r.Next()
+			if err != nil {
+				break
+			}
+			types = append(types, record.Type())
+			// 只读取部分内容，Next应跳过剩余部分
+			head := make([]byte, 8)
+			n, _ := io.ReadFull(record.Content, head)
+			contents = append(contents, string(head[:n]))
+		}
+		result := strings.Join(types, ",") + " " + strings.Join(contents, ",")
+		expected := "warcinfo,response software,HTTP/1.1"
+		if !errors.Is(err, io.EOF) || result != expected {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s WriterReader Failed: %s, expected %q got %q (%v)\n", red("[-]"), table.name, expected, result, err)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s WriterReader Passing: %s \n", green("[+]"), table.name)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		io.WriteString(w, "body{}"+r.URL.Query().Get("q"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	client := &http.Client{Transport: &Transport{Writer: NewWriter(&buf, false)}}
+	tables := []struct {
+		query     string
+		read      bool
+		truncated string
+	}{
+		{"a", true, ""},
+		{"b", false, "unspecified"},
+	}
+	for _, table := range tables {
+		resp, err := client.Get(srv.URL + "/main.css?q=" + table.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if table.read {
+			io.ReadAll(resp.Body)
+		}
+		resp.Body.Close()
+	}
+
+	r, _ := NewReader(&buf)
+	for _, table := range tables {
+		response, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := io.ReadAll(response.Content)
+		request, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok := response.Type() == TypeResponse && request.Type() == TypeRequest &&
+			response.TargetURI() == srv.URL+"/main.css?q="+table.query &&
+			request.Header.Get(FieldConcurrentTo) == response.Header.Get(FieldRecordID) &&
+			response.Header.Get(FieldTruncated) == table.truncated &&
+			strings.HasPrefix(string(block), "HTTP/1.1 200 OK\r\n") &&
+			(!table.read || strings.HasSuffix(string(block), "\r\n\r\nbody{}"+table.query))
+		if !ok {
+			t.Error()
+			red := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("%s Transport Failed: %s, got %v %q\n", red("[-]"), table.query, response.Header, block)
+
+		} else {
+			green := color.New(color.FgGreen).SprintFunc()
+			fmt.Printf("%s Transport Passing: %s \n", green("[+]"), table.query)
+		}
+	}
+}
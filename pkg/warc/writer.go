@@ -0,0 +1,135 @@
+package warc
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/z-bool/go-website-clone/pkg/file"
+)
+
+// Writer 写入WARC记录，可以被多个goroutine同时使用
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+	closer   io.Closer
+}
+
+// NewWriter 创建写入w的Writer，compress为true时每条记录单独压缩为一个gzip成员（.warc.gz）
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: w, compress: compress}
+}
+
+// Create 创建WARC文件并写入warcinfo记录，文件名以.gz结尾时压缩每条记录
+func Create(path string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), file.DirMode); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := NewWriter(f, strings.HasSuffix(path, ".gz"))
+	w.closer = f
+	if err := w.WriteInfo(filepath.Base(path), Header{
+		{Name: "software", Value: "go-website-clone"},
+		{Name: "format", Value: "WARC File Format 1.1"},
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close 关闭Create打开的文件，NewWriter创建的Writer不需要关闭
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closer == nil {
+		return nil
+	}
+	err := w.closer.Close()
+	w.closer = nil
+	return err
+}
+
+// WriteInfo 写入描述归档的warcinfo记录，fields为"名称: 值"形式的内容
+func (w *Writer) WriteInfo(filename string, fields Header) error {
+	var block strings.Builder
+	for _, f := range fields {
+		block.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	header := Header{
+		{Name: FieldType, Value: TypeWarcinfo},
+		{Name: FieldRecordID, Value: NewRecordID()},
+		{Name: FieldDate, Value: FormatDate(time.Now())},
+		{Name: FieldContentType, Value: ContentTypeFields},
+	}
+	if filename != "" {
+		header.Set(FieldFilename, filename)
+	}
+	return w.WriteRecord(header, strings.NewReader(block.String()))
+}
+
+// WriteRecord 写入一条记录，block为记录内容，会被完整读取两次以计算长度和WARC-Block-Digest；
+// header中缺少WARC-Record-ID和WARC-Date时自动补充
+func (w *Writer) WriteRecord(header Header, block io.ReadSeeker) error {
+	digest := newDigest()
+	length, err := io.Copy(digest, block)
+	if err != nil {
+		return err
+	}
+	if _, err := block.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if header.Get(FieldRecordID) == "" {
+		header.Set(FieldRecordID, NewRecordID())
+	}
+	if header.Get(FieldDate) == "" {
+		header.Set(FieldDate, FormatDate(time.Now()))
+	}
+	header.Set(FieldBlockDigest, formatDigest(digest))
+	header.Set(FieldContentLength, strconv.FormatInt(length, 10))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := w.w
+	var gz *gzip.Writer
+	if w.compress {
+		gz = gzip.NewWriter(w.w)
+		out = gz
+	}
+	if _, err := io.WriteString(out, Version+"\r\n"); err != nil {
+		return err
+	}
+	for _, f := range header {
+		if _, err := fmt.Fprintf(out, "%s: %s\r\n", f.Name, f.Value); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(out, "\r\n"); err != nil {
+		return err
+	}
+	n, err := io.Copy(out, block)
+	if err != nil {
+		return err
+	}
+	if n != length {
+		return fmt.Errorf("记录内容在写入时发生变化: %d != %d 字节", n, length)
+	}
+	if _, err := io.WriteString(out, "\r\n\r\n"); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}